@@ -24,14 +24,18 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"go.gearno.de/encoding/base58"
 	"golang.org/x/crypto/pbkdf2"
 )
 
@@ -53,37 +57,125 @@ type (
 		userAgent              string
 		tlsConfig              *tls.Config
 		proxyURL               *url.URL
+
+		commentDecryptionWorkers int
+		decryptPolicy            DecryptPolicy
+		retryPolicy              RetryPolicy
+
+		// transport is closed by Close when ownsTransport is set, that
+		// is when the client created or cloned it.
+		transport     http.RoundTripper
+		ownsTransport bool
+		middlewares   []func(http.RoundTripper) http.RoundTripper
+
+		logger                    *slog.Logger
+		sensitiveHTTPHeaderFields map[string]bool
+
+		// err is the error of options that cannot be applied, it is
+		// returned by every request.
+		err error
 	}
 
 	Option func(c *Client)
 
 	CreatePasteOptions struct {
-		AttachmentName   string
-		Message          []byte
-		Formatter        string
-		Expire           string
+		// AttachmentName sends data as an attachment with this name,
+		// Message becoming the paste text.
+		AttachmentName string
+		Message        []byte
+
+		// Attachments are added to the paste, after the one created with
+		// AttachmentName if any.
+		Attachments []Attachment
+
+		Formatter        Formatter
+		Expire           Expire
 		OpenDiscussion   bool
 		BurnAfterReading bool
 		Compress         CompressionAlgorithm
 		Password         []byte
+
+		// MaxSize is the maximum number of bytes CreatePasteFromReader
+		// reads from its reader, zero means no limit.
+		MaxSize int64
+	}
+
+	CreateCommentOptions struct {
+		Compress CompressionAlgorithm
+		Password []byte
 	}
 
 	ShowPasteOptions struct {
 		Password    []byte
 		ConfirmBurn bool
+
+		// SkipComments skips the decryption of the comments, the result
+		// only carries the comment count.
+		SkipComments bool
+
+		// PromptPassword is called when the paste cannot be decrypted
+		// with Password, it returns the password to try next. The paste
+		// is not fetched again, so burn after reading pastes can be
+		// retried. Returning an error stops the attempts.
+		PromptPassword func(ctx context.Context) ([]byte, error)
+	}
+
+	FetchEnvelopeOptions struct {
+		ConfirmBurn bool
+	}
+
+	// Envelope is a paste as stored by the server, before decryption.
+	Envelope struct {
+		PasteID string
+
+		// EncryptedPaste holds the authenticated data and the cipher
+		// text, it can be decrypted with Open. The server does not
+		// return the expire option, Meta is left empty.
+		EncryptedPaste EncryptedPaste
+
+		// Created is the creation time of the paste, zero when the server
+		// does not disclose it.
+		Created time.Time
+
+		// TimeToLive is the remaining time before the paste expires, zero
+		// when the paste never expires.
+		TimeToLive time.Duration
+
+		CommentCount int
 	}
 
 	CreatePasteResult struct {
 		PasteID     string
 		PasteURL    url.URL
+		PasteRef    PasteRef
 		DeleteToken string
 	}
 
+	CreateCommentResult struct {
+		CommentID string
+		PasteID   string
+		ParentID  string
+	}
+
 	ShowPasteResult struct {
 		PasteID      string
 		CommentCount int
 		Paste        Paste
 		Comments     []Comment
+
+		// Created is the creation time of the paste, zero when the server
+		// does not disclose it.
+		Created time.Time
+
+		// TimeToLive is the remaining time before the paste expires, zero
+		// when the paste never expires.
+		TimeToLive time.Duration
+
+		// Formatter is FormatterUnknown when the server returns a value
+		// this package does not know.
+		Formatter        Formatter
+		OpenDiscussion   bool
+		BurnAfterReading bool
 	}
 
 	Comment struct {
@@ -92,17 +184,20 @@ type (
 		ParentID  string
 		Nickname  string
 		Text      string
-	}
 
-	createPasteRequest struct {
-		V     int                    `json:"v"`
-		AData AData                  `json:"adata"`
-		Meta  createPasteRequestMeta `json:"meta"`
-		CT    string                 `json:"ct"`
-	}
+		// Created is the creation time of the comment, zero when the
+		// server does not disclose it.
+		Created time.Time
+
+		// Icon is the avatar generated by the server for the comment
+		// author, usually a "data:image/png;base64," URL.
+		Icon string
 
-	createPasteRequestMeta struct {
-		Expire string `json:"expire"`
+		// Err is set when the comment cannot be decrypted or decoded,
+		// Nickname and Text are then empty. Anyone can comment a paste
+		// with open discussion, so a broken comment does not prevent
+		// reading the paste and the other comments.
+		Err error
 	}
 
 	createPasteResponse struct {
@@ -113,6 +208,42 @@ type (
 		DeleteToken string `json:"deletetoken"`
 	}
 
+	createCommentRequest struct {
+		V        int    `json:"v"`
+		AData    Spec   `json:"adata"`
+		CT       string `json:"ct"`
+		PasteID  string `json:"pasteid"`
+		ParentID string `json:"parentid"`
+	}
+
+	createCommentResponse struct {
+		ID      string `json:"id"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+	}
+
+	commentMessage struct {
+		Comment  string `json:"comment"`
+		Nickname string `json:"nickname,omitempty"`
+	}
+
+	responseStatus struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+
+	deletePasteRequest struct {
+		PasteID     string `json:"pasteid"`
+		DeleteToken string `json:"deletetoken"`
+	}
+
+	deletePasteResponse struct {
+		ID      string `json:"id"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+
 	showPasteRequestMeta struct {
 		Created    int `json:"created"`
 		TimeToLive int `json:"time_to_live"`
@@ -200,135 +331,359 @@ func WithProxyURL(proxyURL url.URL) Option {
 	}
 }
 
+// WithHTTPClient sets the HTTP client used to send requests instead of a
+// client with a dedicated pooled transport. The client is not modified:
+// when WithTLSConfig or WithProxyURL are also given, its transport must be
+// an *http.Transport (or nil for http.DefaultTransport) and is cloned to
+// apply them, otherwise every request of the client fails. Close leaves
+// the transport of the client alone unless it was cloned.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTransportMiddleware wraps the transport of the HTTP client, for
+// example to add authentication or record the traffic. Middlewares are
+// applied in order, the first one being the outermost.
+func WithTransportMiddleware(middleware func(http.RoundTripper) http.RoundTripper) Option {
+	return func(c *Client) {
+		c.middlewares = append(c.middlewares, middleware)
+	}
+}
+
+// WithCommentDecryptionWorkers sets the number of comments decrypted
+// concurrently by ShowPaste. It defaults to GOMAXPROCS.
+func WithCommentDecryptionWorkers(n int) Option {
+	return func(c *Client) {
+		c.commentDecryptionWorkers = n
+	}
+}
+
+// WithDecryptPolicy sets the policy enforced on the encryption parameters and
+// sizes sent by the server. It defaults to DefaultDecryptPolicy.
+func WithDecryptPolicy(policy DecryptPolicy) Option {
+	return func(c *Client) {
+		c.decryptPolicy = policy
+	}
+}
+
 func NewClient(endpoint url.URL, options ...Option) *Client {
 	client := &Client{
-		endpoint:               endpoint,
-		customHTTPHeaderFields: make(map[string]string),
+		endpoint:                  endpoint,
+		customHTTPHeaderFields:    make(map[string]string),
+		sensitiveHTTPHeaderFields: make(map[string]bool),
+		logger:                    discardLogger,
+		commentDecryptionWorkers:  runtime.GOMAXPROCS(0),
+		decryptPolicy:             DefaultDecryptPolicy(),
 	}
 
 	for _, option := range options {
 		option(client)
 	}
 
-	client.httpClient = defaultPooledClient(client.tlsConfig, client.proxyURL)
+	if client.commentDecryptionWorkers < 1 {
+		client.commentDecryptionWorkers = 1
+	}
+
+	httpClient, err := client.newHTTPClient()
+	if err != nil {
+		client.err = err
+		return client
+	}
+
+	client.httpClient = httpClient
 
 	return client
 }
 
+// newHTTPClient builds the HTTP client from the options: the transport of
+// the client given with WithHTTPClient, or a pooled one, configured with
+// the TLS and proxy options and wrapped by the middlewares.
+func (c *Client) newHTTPClient() (*http.Client, error) {
+	if c.httpClient == nil {
+		c.transport = defaultPooledTransport(c.tlsConfig, c.proxyURL)
+		c.ownsTransport = true
+		return &http.Client{Transport: c.wrapTransport(c.transport)}, nil
+	}
+
+	httpClient := *c.httpClient
+
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if c.tlsConfig != nil || c.proxyURL != nil {
+		t, ok := transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("cannot apply TLS config or proxy url: transport %T is not an *http.Transport", transport)
+		}
+
+		t = t.Clone()
+
+		if c.tlsConfig != nil {
+			t.TLSClientConfig = c.tlsConfig
+		}
+
+		if c.proxyURL != nil {
+			t.Proxy = http.ProxyURL(c.proxyURL)
+		}
+
+		transport = t
+		c.ownsTransport = true
+	}
+
+	c.transport = transport
+	httpClient.Transport = c.wrapTransport(transport)
+
+	return &httpClient, nil
+}
+
+func (c *Client) wrapTransport(transport http.RoundTripper) http.RoundTripper {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		transport = c.middlewares[i](transport)
+	}
+
+	return transport
+}
+
+// Close releases the idle connections of the HTTP transport when the
+// client created it, a transport given with WithHTTPClient is shared and
+// left alone. The client can still be used afterwards.
+func (c *Client) Close() error {
+	if !c.ownsTransport {
+		return nil
+	}
+
+	if t, ok := c.transport.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+
+	return nil
+}
+
 func (c *Client) ShowPaste(
 	ctx context.Context,
 	urlWithMasterKey url.URL,
 	opts ShowPasteOptions,
 ) (*ShowPasteResult, error) {
-	fragment := urlWithMasterKey.Fragment
-	if strings.HasPrefix(urlWithMasterKey.Fragment, "-") {
-		fragment = urlWithMasterKey.Fragment[1:]
+	ref, err := parsePasteRef(urlWithMasterKey)
+	if err != nil {
+		return nil, err
+	}
 
-		if !opts.ConfirmBurn {
-			return nil, fmt.Errorf("cannot read a paste that is set to be burned after reading")
-		}
+	if ref.MasterKey == nil {
+		return nil, fmt.Errorf("cannot show paste: missing master key")
 	}
 
-	masterKey, err := base58.Decode(fragment)
-	if err != nil {
-		return nil, fmt.Errorf("cannot decode master key: %w", err)
+	if ref.BurnAfterReading && !opts.ConfirmBurn {
+		return nil, ErrBurnNotConfirmed
 	}
 
-	urlWithoutMasterKey := urlWithMasterKey
-	urlWithoutMasterKey.Fragment = ""
+	masterKey := ref.MasterKey
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodGet,
-		urlWithoutMasterKey.String(),
-		nil,
-	)
+	pasteResponse, err := c.fetchPaste(ctx, ref)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create request: %w", err)
+		return nil, err
 	}
 
-	for k, v := range c.customHTTPHeaderFields {
-		req.Header.Set(k, v)
+	encryptedPaste := EncryptedPaste{
+		V:     pasteResponse.V,
+		AData: pasteResponse.AData,
+		CT:    pasteResponse.CT,
 	}
 
-	req.Header.Set("User-Agent", c.userAgent)
-	req.Header.Set("X-Requested-With", "JSONHttpRequest")
+	password := opts.Password
+	paste, err := c.decryptPolicy.open(encryptedPaste, masterKey, password, c.logger)
+	for errors.Is(err, ErrDecryptionFailed) && opts.PromptPassword != nil {
+		password, err = opts.PromptPassword(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot prompt password: %w", err)
+		}
 
-	if c.username != "" || c.password != "" {
-		req.SetBasicAuth(c.username, c.password)
+		paste, err = c.decryptPolicy.open(encryptedPaste, masterKey, password, c.logger)
 	}
-
-	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("cannot execute http request: %w", err)
+		return nil, err
 	}
-	defer func() { _ = res.Body.Close() }()
 
-	var pasteResponse showPasteResponse
+	var comments []Comment
+	if !opts.SkipComments {
+		comments, err = c.decryptComments(ctx, masterKey.withPassword(password), pasteResponse.Comments)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var created time.Time
+	if pasteResponse.Meta.Created > 0 {
+		created = time.Unix(int64(pasteResponse.Meta.Created), 0)
+	}
+
+	// An unknown formatter is not an error, the paste is still readable.
+	formatter, _ := ParseFormatter(pasteResponse.AData.Formatter)
+
+	return &ShowPasteResult{
+		PasteID:          pasteResponse.ID,
+		CommentCount:     pasteResponse.CommentCount,
+		Paste:            paste,
+		Comments:         comments,
+		Created:          created,
+		TimeToLive:       time.Duration(pasteResponse.Meta.TimeToLive) * time.Second,
+		Formatter:        formatter,
+		OpenDiscussion:   pasteResponse.AData.OpenDiscussion,
+		BurnAfterReading: pasteResponse.AData.BurnAfterReading,
+	}, nil
+}
 
-	err = json.NewDecoder(res.Body).Decode(&pasteResponse)
+// FetchEnvelope fetches a paste without decrypting it, the master key is
+// not needed. PrivateBin deletes burn after reading pastes once fetched,
+// they are refused unless opts.ConfirmBurn is set. Only URLs carrying the
+// key with its "-" marker tell them apart: pastes behind other URLs are
+// fetched, and deleted when they are burn after reading, so callers
+// should confirm before calling FetchEnvelope with such URLs.
+func (c *Client) FetchEnvelope(
+	ctx context.Context,
+	pasteURL url.URL,
+	opts FetchEnvelopeOptions,
+) (*Envelope, error) {
+	ref, err := parsePasteRef(pasteURL)
 	if err != nil {
-		return nil, fmt.Errorf("cannot decode response body: %w", err)
+		return nil, err
 	}
 
-	if pasteResponse.Status != 0 {
-		return nil, fmt.Errorf("cannot load paste: server respond with %d status: %s", pasteResponse.Status, pasteResponse.Message)
+	if ref.BurnAfterReading && !opts.ConfirmBurn {
+		return nil, ErrBurnNotConfirmed
 	}
 
-	authData, err := json.Marshal(pasteResponse.AData)
+	pasteResponse, err := c.fetchPaste(ctx, ref)
 	if err != nil {
-		return nil, fmt.Errorf("cannot encode adata: %w", err)
+		return nil, err
 	}
 
-	masterKeyWithPassword := append(masterKey, opts.Password...)
-	cipherText, err := decrypt(masterKeyWithPassword, pasteResponse.CT, authData, pasteResponse.AData.Spec)
-	if err != nil {
-		return nil, fmt.Errorf("cannot decrypt data: %w", err)
+	var created time.Time
+	if pasteResponse.Meta.Created > 0 {
+		created = time.Unix(int64(pasteResponse.Meta.Created), 0)
 	}
 
-	var paste Paste
-	err = json.Unmarshal(cipherText, &paste)
+	return &Envelope{
+		PasteID: pasteResponse.ID,
+		EncryptedPaste: EncryptedPaste{
+			V:     pasteResponse.V,
+			AData: pasteResponse.AData,
+			CT:    pasteResponse.CT,
+		},
+		Created:      created,
+		TimeToLive:   time.Duration(pasteResponse.Meta.TimeToLive) * time.Second,
+		CommentCount: pasteResponse.CommentCount,
+	}, nil
+}
+
+func (c *Client) fetchPaste(ctx context.Context, ref PasteRef) (*showPasteResponse, error) {
+	ref.MasterKey = nil
+	req, err := c.newRequest(ctx, http.MethodGet, ref.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("cannot unmarshal paste content: %w", err)
+		return nil, err
 	}
 
-	var comments []Comment
-	for i, comment := range pasteResponse.Comments {
-		authData, err := json.Marshal(comment.AData)
-		if err != nil {
-			return nil, fmt.Errorf("cannot encode comment (#%d) adata: %w", i, err)
-		}
+	var pasteResponse showPasteResponse
+	if err := c.do(req, true, &pasteResponse); err != nil {
+		return nil, fmt.Errorf("cannot load paste: %w", err)
+	}
 
-		data, err := decrypt(masterKeyWithPassword, comment.CT, authData, comment.AData)
-		if err != nil {
-			return nil, fmt.Errorf("cannot decrypt comment (#%d): %w", i, err)
-		}
+	return &pasteResponse, nil
+}
 
-		var message map[string]string
-		err = json.Unmarshal(data, &message)
-		if err != nil {
-			return nil, fmt.Errorf("cannot decode comment (#%d): %w", i, err)
-		}
+// decryptComments decrypts the comments on a bounded pool of workers, as
+// each comment has its own salt and requires a full key derivation. The
+// order of the comments is preserved and the error of every comment that
+// cannot be decrypted is reported.
+func (c *Client) decryptComments(
+	ctx context.Context,
+	masterKey []byte,
+	encryptedComments []showPasteResponseComment,
+) ([]Comment, error) {
+	if len(encryptedComments) == 0 {
+		return nil, nil
+	}
 
-		comments = append(
-			comments,
-			Comment{
-				CommentID: comment.ID,
-				PasteID:   comment.PasteID,
-				ParentID:  comment.ParentID,
-				Nickname:  message["nickname"],
-				Text:      message["comment"],
-			},
-		)
+	if err := c.decryptPolicy.checkCommentIterations(encryptedComments); err != nil {
+		return nil, fmt.Errorf("cannot decrypt comments: %w", err)
+	}
+
+	var (
+		comments = make([]Comment, len(encryptedComments))
+		jobs     = make(chan int)
+		wg       sync.WaitGroup
+	)
 
+	workers := min(c.commentDecryptionWorkers, len(encryptedComments))
+	for range workers {
+		wg.Go(func() {
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				comments[i] = decryptComment(masterKey, i, encryptedComments[i], c.decryptPolicy, c.logger)
+			}
+		})
 	}
 
-	return &ShowPasteResult{
-		PasteID:      pasteResponse.ID,
-		CommentCount: pasteResponse.CommentCount,
-		Paste:        paste,
-		Comments:     comments,
-	}, nil
+	for i := range encryptedComments {
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("cannot decrypt comments: %w", err)
+	}
+
+	return comments, nil
+}
+
+// decryptComment decrypts the comment, failures are reported in the Err
+// field of the returned comment.
+func decryptComment(masterKey []byte, i int, comment showPasteResponseComment, policy DecryptPolicy, logger *slog.Logger) Comment {
+	var created time.Time
+	if comment.Meta.Created > 0 {
+		created = time.Unix(int64(comment.Meta.Created), 0)
+	}
+
+	result := Comment{
+		CommentID: comment.ID,
+		PasteID:   comment.PasteID,
+		ParentID:  comment.ParentID,
+		Created:   created,
+		Icon:      comment.Meta.Icon,
+	}
+
+	authData, err := json.Marshal(comment.AData)
+	if err != nil {
+		result.Err = fmt.Errorf("cannot encode comment (#%d) adata: %w", i, err)
+		return result
+	}
+
+	data, err := decrypt(masterKey, comment.CT, authData, comment.AData, policy, logger)
+	if err != nil {
+		result.Err = fmt.Errorf("cannot decrypt comment (#%d): %w", i, err)
+		return result
+	}
+
+	var message map[string]string
+	err = json.Unmarshal(data, &message)
+	if err != nil {
+		result.Err = fmt.Errorf("cannot decode comment (#%d): %w", i, err)
+		return result
+	}
+
+	result.Nickname = message["nickname"]
+	result.Text = message["comment"]
+
+	return result
 }
 
 func (c *Client) CreatePaste(
@@ -336,174 +691,441 @@ func (c *Client) CreatePaste(
 	data []byte,
 	opts CreatePasteOptions,
 ) (*CreatePasteResult, error) {
-	var paste Paste
-
+	paste := Paste{Data: data}
 	if opts.AttachmentName != "" {
-		paste = Paste{opts.Message, data, opts.AttachmentName, ""}
-	} else {
-		paste = Paste{data, nil, "", ""}
+		paste = Paste{
+			Data:        opts.Message,
+			Attachments: []Attachment{{Name: opts.AttachmentName, Data: data}},
+		}
 	}
 
-	pasteData, err := json.Marshal(&paste)
+	paste.Attachments = append(paste.Attachments, opts.Attachments...)
+
+	encryptedPaste, masterKey, err := seal(
+		paste,
+		SealOptions{
+			Formatter:        opts.Formatter,
+			Expire:           opts.Expire,
+			OpenDiscussion:   opts.OpenDiscussion,
+			BurnAfterReading: opts.BurnAfterReading,
+			Compress:         opts.Compress,
+			Password:         opts.Password,
+		},
+		c.logger,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot json marshal paste content: %w", err)
+		return nil, err
 	}
 
-	masterKey, err := generateRandomBytes(32)
+	var reqBody bytes.Buffer
+	err = json.NewEncoder(&reqBody).Encode(&encryptedPaste)
 	if err != nil {
-		return nil, fmt.Errorf("cannot generate random bytes: %w", err)
+		return nil, fmt.Errorf("cannot marshal paste request: %w", err)
 	}
 
-	iv, err := generateRandomBytes(12)
+	body := reqBody.Bytes()
+	return c.sendPaste(
+		ctx,
+		func() io.Reader { return bytes.NewReader(body) },
+		int64(len(body)),
+		masterKey,
+		opts.BurnAfterReading,
+	)
+}
+
+// sendPaste posts an encrypted paste and builds the paste link from the
+// server response. newBody returns a fresh copy of the request body each
+// time it is called, so the request can be sent again.
+func (c *Client) sendPaste(
+	ctx context.Context,
+	newBody func() io.Reader,
+	contentLength int64,
+	masterKey MasterKey,
+	burnAfterReading bool,
+) (*CreatePasteResult, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, c.endpoint.String(), newBody())
 	if err != nil {
-		return nil, fmt.Errorf("cannot generate iv: %w", err)
+		return nil, err
 	}
 
-	salt, err := generateRandomBytes(8)
+	req.ContentLength = contentLength
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(newBody()), nil }
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Content-Length", strconv.FormatInt(contentLength, 10))
+
+	var pasteResponse createPasteResponse
+	if err := c.do(req, false, &pasteResponse); err != nil {
+		return nil, fmt.Errorf("cannot create paste: %w", err)
+	}
+
+	pasteURL, err := url.Parse(pasteResponse.URL)
 	if err != nil {
-		return nil, fmt.Errorf("cannot generate salt: %w", err)
+		return nil, fmt.Errorf("cannot parse paste url: %w", err)
 	}
 
-	masterKeyWithPassword := append(masterKey, opts.Password...)
-	key := pbkdf2.Key(masterKeyWithPassword, salt, iterationCount, keySize/8, sha256.New)
+	pasteID := pasteResponse.ID
+	if pasteID == "" {
+		pasteID = pasteURL.RawQuery
+	}
 
-	if opts.Compress == CompressionAlgorithmGZip {
-		var buf bytes.Buffer
-		fw, err := flate.NewWriter(&buf, flate.BestCompression)
-		if err != nil {
-			return nil, fmt.Errorf("cannot create new flate writer: %w", err)
-		}
+	if !isPasteID(pasteID) {
+		return nil, fmt.Errorf("cannot create paste: invalid paste id %q", pasteID)
+	}
 
-		if _, err := fw.Write(pasteData); err != nil {
-			return nil, fmt.Errorf("cannot write in flate buf: %w", err)
-		}
+	ref := PasteRef{
+		Instance: url.URL{
+			Scheme: c.endpoint.Scheme,
+			Host:   c.endpoint.Host,
+			Path:   c.endpoint.Path,
+		},
+		PasteID:          pasteID,
+		MasterKey:        masterKey,
+		BurnAfterReading: burnAfterReading,
+	}
 
-		if err := fw.Close(); err != nil {
-			return nil, fmt.Errorf("cannot close flate writer: %w", err)
-		}
+	return &CreatePasteResult{
+		PasteID:     pasteID,
+		PasteURL:    ref.URL(),
+		PasteRef:    ref,
+		DeleteToken: pasteResponse.DeleteToken,
+	}, nil
+}
 
-		pasteData = buf.Bytes()
+func (c *Client) CreateComment(
+	ctx context.Context,
+	pasteURL url.URL,
+	parentID string,
+	nickname string,
+	text string,
+	opts CreateCommentOptions,
+) (*CreateCommentResult, error) {
+	ref, err := parsePasteRef(pasteURL)
+	if err != nil {
+		return nil, err
 	}
 
-	adata := AData{
-		Spec{
-			iv,
-			salt,
-			iterationCount,
-			keySize,
-			tagSize,
-			EncryptionAlgorithmAES,
-			EncryptionModeGCM,
-			opts.Compress,
-		},
-		opts.Formatter,
-		opts.OpenDiscussion,
-		opts.BurnAfterReading,
+	if ref.MasterKey == nil {
+		return nil, fmt.Errorf("cannot create comment: missing master key")
+	}
+
+	masterKey, pasteID := ref.MasterKey, ref.PasteID
+
+	// A comment without parent is a reply to the paste itself.
+	if parentID == "" {
+		parentID = pasteID
+	}
+
+	data, err := json.Marshal(&commentMessage{Comment: text, Nickname: nickname})
+	if err != nil {
+		return nil, fmt.Errorf("cannot json marshal comment content: %w", err)
+	}
+
+	spec, err := newSpec(opts.Compress)
+	if err != nil {
+		return nil, err
 	}
 
-	authData, err := json.Marshal(adata)
+	authData, err := json.Marshal(spec)
 	if err != nil {
 		return nil, fmt.Errorf("cannot encode adata: %w", err)
 	}
 
-	cipherBlock, err := aes.NewCipher(key)
+	cipherText, err := encrypt(masterKey.withPassword(opts.Password), data, authData, spec, c.logger)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create new cipher: %w", err)
+		return nil, fmt.Errorf("cannot encrypt data: %w", err)
 	}
 
-	gcm, err := cipher.NewGCM(cipherBlock)
+	var reqBody bytes.Buffer
+	err = json.NewEncoder(&reqBody).Encode(
+		&createCommentRequest{
+			V:        apiVersion,
+			AData:    spec,
+			CT:       cipherText,
+			PasteID:  pasteID,
+			ParentID: parentID,
+		},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create new galois counter mode: %w", err)
+		return nil, fmt.Errorf("cannot marshal comment request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, ref.Instance.String(), &reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Content-Length", strconv.Itoa(reqBody.Len()))
+
+	var commentResponse createCommentResponse
+	if err := c.do(req, false, &commentResponse); err != nil {
+		return nil, fmt.Errorf("cannot create comment: %w", err)
+	}
+
+	return &CreateCommentResult{
+		CommentID: commentResponse.ID,
+		PasteID:   pasteID,
+		ParentID:  parentID,
+	}, nil
+}
+
+func (c *Client) DeletePaste(
+	ctx context.Context,
+	pasteURLOrID string,
+	deleteToken string,
+) error {
+	endpoint := c.endpoint
+	pasteID := pasteURLOrID
+
+	if strings.Contains(pasteURLOrID, "://") {
+		pasteURL, err := url.Parse(pasteURLOrID)
+		if err != nil {
+			return fmt.Errorf("cannot parse paste url: %w", err)
+		}
+
+		// The key is not needed to delete a paste.
+		pasteURL.Fragment = ""
+
+		ref, err := parsePasteRef(*pasteURL)
+		if err != nil {
+			return err
+		}
+
+		endpoint, pasteID = ref.Instance, ref.PasteID
 	}
 
-	cipherText := gcm.Seal(nil, iv, pasteData, authData)
+	if pasteID == "" {
+		return fmt.Errorf("cannot delete paste: missing paste id")
+	}
 
-	createPasteReq := &createPasteRequest{
-		V:     apiVersion,
-		AData: adata,
-		Meta:  createPasteRequestMeta{Expire: opts.Expire},
-		CT:    base64.StdEncoding.EncodeToString(cipherText),
+	if deleteToken == "" {
+		return fmt.Errorf("cannot delete paste: missing delete token")
 	}
 
 	var reqBody bytes.Buffer
-	err = json.NewEncoder(&reqBody).Encode(createPasteReq)
+	err := json.NewEncoder(&reqBody).Encode(
+		&deletePasteRequest{
+			PasteID:     pasteID,
+			DeleteToken: deleteToken,
+		},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("cannot marshal paste request: %w", err)
+		return fmt.Errorf("cannot marshal delete request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		c.endpoint.String(),
-		&reqBody,
-	)
+	req, err := c.newRequest(ctx, http.MethodPost, endpoint.String(), &reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create request: %w", err)
+		return err
 	}
 
-	for k, v := range c.customHTTPHeaderFields {
-		req.Header.Set(k, v)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Content-Length", strconv.Itoa(reqBody.Len()))
+
+	var deleteResponse deletePasteResponse
+	if err := c.do(req, false, &deleteResponse); err != nil {
+		return fmt.Errorf("cannot delete paste: %w", err)
+	}
+
+	return nil
+}
+
+// decodeResponse decodes a PrivateBin JSON response into v. Responses with a
+// non-zero PrivateBin status, a non-2xx HTTP status code or a body that is
+// not JSON are reported as a *ServerError. Bodies larger than limit bytes
+// are rejected with a *PolicyViolationError before being read entirely, a
+// zero limit disables the check.
+func decodeResponse(res *http.Response, v any, limit int64) error {
+	r := io.Reader(res.Body)
+	if limit > 0 {
+		r = io.LimitReader(res.Body, limit+1)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("cannot read response body: %w", err)
+	}
+
+	if limit > 0 && int64(len(body)) > limit {
+		return &PolicyViolationError{"response size", int64(len(body))}
+	}
+
+	var status responseStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return newServerError(res, 0, "")
+		}
+
+		return newServerError(res, 0, fmt.Sprintf("invalid response body: %s", err))
+	}
+
+	if status.Status != 0 || res.StatusCode < 200 || res.StatusCode > 299 {
+		return newServerError(res, status.Status, status.Message)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("cannot decode response body: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(
+	ctx context.Context,
+	method string,
+	rawURL string,
+	body io.Reader,
+) (*http.Request, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
 	}
 
 	if c.userAgent != "" {
 		req.Header.Set("User-Agent", c.userAgent)
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Content-Length", strconv.Itoa(reqBody.Len()))
 	req.Header.Set("X-Requested-With", "JSONHttpRequest")
 
+	// Paste URLs may name another instance than the endpoint, the
+	// credentials and custom header fields are only sent to the endpoint.
+	if !sameOrigin(c.endpoint, *req.URL) {
+		return req, nil
+	}
+
+	for k, v := range c.customHTTPHeaderFields {
+		req.Header.Set(k, v)
+	}
+
 	if c.username != "" || c.password != "" {
 		req.SetBasicAuth(c.username, c.password)
 	}
 
-	res, err := c.httpClient.Do(req)
+	return req, nil
+}
+
+// sameOrigin reports whether both URLs have the same scheme and host,
+// ignoring case and default ports.
+func sameOrigin(a, b url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) && originHost(a) == originHost(b)
+}
+
+func originHost(u url.URL) string {
+	host := strings.ToLower(u.Hostname())
+
+	switch port := u.Port(); {
+	case port == "":
+	case strings.EqualFold(u.Scheme, "http") && port == "80":
+	case strings.EqualFold(u.Scheme, "https") && port == "443":
+	default:
+		host += ":" + port
+	}
+
+	return host
+}
+
+func newSpec(compress CompressionAlgorithm) (Spec, error) {
+	if compress == CompressionAlgorithmUnknow {
+		compress = CompressionAlgorithmNone
+	}
+
+	iv, err := generateRandomBytes(12)
 	if err != nil {
-		return nil, fmt.Errorf("cannot execute http request: %w", err)
+		return Spec{}, fmt.Errorf("cannot generate iv: %w", err)
 	}
-	defer func() { _ = res.Body.Close() }()
 
-	pasteResponse := createPasteResponse{}
-	err = json.NewDecoder(res.Body).Decode(&pasteResponse)
+	salt, err := generateRandomBytes(8)
 	if err != nil {
-		return nil, fmt.Errorf("cannot decode response body: %w", err)
+		return Spec{}, fmt.Errorf("cannot generate salt: %w", err)
 	}
 
-	if pasteResponse.Status != 0 {
-		return nil, fmt.Errorf("cannot create paste: server respond with %d status: %s", pasteResponse.Status, pasteResponse.Message)
+	return Spec{
+		iv,
+		salt,
+		iterationCount,
+		keySize,
+		tagSize,
+		EncryptionAlgorithmAES,
+		EncryptionModeGCM,
+		compress,
+	}, nil
+}
+
+func encrypt(masterKey []byte, data []byte, adata []byte, spec Spec, logger *slog.Logger) (string, error) {
+	if spec.Compression == CompressionAlgorithmGZip {
+		start := time.Now()
+
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, flate.BestCompression)
+		if err != nil {
+			return "", fmt.Errorf("cannot create new flate writer: %w", err)
+		}
+
+		if _, err := fw.Write(data); err != nil {
+			return "", fmt.Errorf("cannot write in flate buf: %w", err)
+		}
+
+		if err := fw.Close(); err != nil {
+			return "", fmt.Errorf("cannot close flate writer: %w", err)
+		}
+
+		logDuration(logger, "compression", start, "size", len(data), "compressed_size", buf.Len())
+
+		data = buf.Bytes()
 	}
 
-	pasteID, err := url.Parse(pasteResponse.URL)
+	cipherText, err := sealData(masterKey, nil, data, adata, spec, logger)
 	if err != nil {
-		return nil, fmt.Errorf("cannot parse paste url: %w", err)
+		return "", err
 	}
 
-	fragment := base58.Encode(masterKey)
-	if opts.BurnAfterReading {
-		fragment = "-" + fragment
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+// sealData derives the key from the master key and appends the AES-GCM
+// encryption of data to dst. Passing data[:0] as dst encrypts in place
+// when data has room for the tag.
+func sealData(masterKey []byte, dst, data, adata []byte, spec Spec, logger *slog.Logger) ([]byte, error) {
+	start := time.Now()
+	key := pbkdf2.Key(masterKey, spec.Salt, spec.Iterations, spec.KeySize/8, sha256.New)
+	logDuration(logger, "key derivation", start, "iterations", spec.Iterations)
+
+	cipherBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create new cipher: %w", err)
 	}
 
-	pasteLink := url.URL{
-		Scheme:   c.endpoint.Scheme,
-		Host:     c.endpoint.Host,
-		Path:     c.endpoint.Path,
-		RawQuery: pasteID.RawQuery,
-		Fragment: fragment,
+	gcm, err := cipher.NewGCM(cipherBlock)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create new galois counter mode: %w", err)
 	}
 
-	return &CreatePasteResult{
-		PasteID:     pasteResponse.ID,
-		PasteURL:    pasteLink,
-		DeleteToken: pasteResponse.DeleteToken,
-	}, nil
+	start = time.Now()
+	cipherText := gcm.Seal(dst, spec.IV, data, adata)
+	logDuration(logger, "encryption", start, "size", len(cipherText))
+
+	return cipherText, nil
 }
 
-func decrypt(masterKey []byte, ct string, adata []byte, spec Spec) ([]byte, error) {
+func decrypt(masterKey []byte, ct string, adata []byte, spec Spec, policy DecryptPolicy, logger *slog.Logger) ([]byte, error) {
+	if err := policy.checkSpec(spec); err != nil {
+		return nil, err
+	}
+
+	// Check the size announced by the base64 encoding before decoding it.
+	if err := policy.checkCipherTextSize(int64(base64.StdEncoding.DecodedLen(len(ct)))); err != nil {
+		return nil, err
+	}
+
 	encryptedCipherText, err := decode64(ct)
 	if err != nil {
 		return nil, fmt.Errorf("cannot base64 decode cipher text: %w", err)
 	}
 
+	start := time.Now()
 	key := pbkdf2.Key(
 		masterKey,
 		spec.Salt,
@@ -511,6 +1133,7 @@ func decrypt(masterKey []byte, ct string, adata []byte, spec Spec) ([]byte, erro
 		spec.KeySize/8,
 		sha256.New,
 	)
+	logDuration(logger, "key derivation", start, "iterations", spec.Iterations)
 
 	var (
 		cipherBlock cipher.Block
@@ -541,21 +1164,39 @@ func decrypt(masterKey []byte, ct string, adata []byte, spec Spec) ([]byte, erro
 		return nil, fmt.Errorf("unsupported encryption mode: %q", spec.Mode)
 	}
 
+	start = time.Now()
 	cipherText, err := gcm.Open(nil, spec.IV, encryptedCipherText, adata)
 	if err != nil {
-		return nil, err
+		return nil, ErrDecryptionFailed
 	}
+	logDuration(logger, "decryption", start, "size", len(encryptedCipherText))
 
 	switch spec.Compression {
 	case CompressionAlgorithmNone:
+		if policy.MaxDecompressedSize > 0 && int64(len(cipherText)) > policy.MaxDecompressedSize {
+			return nil, &PolicyViolationError{"decompressed size", int64(len(cipherText))}
+		}
 	case CompressionAlgorithmGZip:
-		buf := bytes.NewBuffer(cipherText)
-		fr := flate.NewReader(buf)
+		fr := flate.NewReader(bytes.NewReader(cipherText))
 		defer func() { _ = fr.Close() }()
-		cipherText, err = io.ReadAll(fr)
+
+		var r io.Reader = fr
+		if policy.MaxDecompressedSize > 0 {
+			// Read one byte past the limit to detect oversized data
+			// without inflating it entirely.
+			r = io.LimitReader(fr, policy.MaxDecompressedSize+1)
+		}
+
+		start = time.Now()
+		cipherText, err = io.ReadAll(r)
 		if err != nil {
 			return nil, fmt.Errorf("cannot read gzip: %w", err)
 		}
+		logDuration(logger, "decompression", start, "size", len(cipherText))
+
+		if policy.MaxDecompressedSize > 0 && int64(len(cipherText)) > policy.MaxDecompressedSize {
+			return nil, &PolicyViolationError{"decompressed size", int64(len(cipherText))}
+		}
 	default:
 		return nil, fmt.Errorf("unsupported compression mode: %q", spec.Compression)
 	}
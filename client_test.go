@@ -15,11 +15,23 @@
 package privatebin
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.gearno.de/encoding/base58"
 )
 
 func TestShowPasteRequestMeta_UnmarshalJSON(t *testing.T) {
@@ -78,3 +90,767 @@ func TestShowPasteRequestMeta_UnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestPasteIDFromURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "Bare query string",
+			input: "https://privatebin.net/?f468483c313401e8#mk",
+			want:  "f468483c313401e8",
+		},
+		{
+			name:  "Pasteid query parameter",
+			input: "https://privatebin.net/?pasteid=f468483c313401e8",
+			want:  "f468483c313401e8",
+		},
+		{
+			name:    "No query string",
+			input:   "https://privatebin.net/#mk",
+			wantErr: true,
+		},
+		{
+			name:    "Unrelated query parameter",
+			input:   "https://privatebin.net/?foo=bar",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.input)
+			require.NoError(t, err)
+
+			got, err := pasteIDFromURL(*u)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.NotContains(t, err.Error(), "mk")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewSpec_DefaultCompression(t *testing.T) {
+	spec, err := newSpec(CompressionAlgorithmUnknow)
+	require.NoError(t, err)
+	assert.Equal(t, CompressionAlgorithmNone, spec.Compression)
+}
+
+func TestClient_DeletePaste(t *testing.T) {
+	tests := []struct {
+		name     string
+		byURL    bool
+		response string
+		wantErr  error
+	}{
+		{
+			name:     "Deleted by paste id",
+			response: `{"status":0,"id":"f468483c313401e8"}`,
+		},
+		{
+			name:     "Deleted by paste url",
+			byURL:    true,
+			response: `{"status":0,"id":"f468483c313401e8"}`,
+		},
+		{
+			name:     "Wrong delete token",
+			response: `{"status":1,"message":"Wrong deletion token. Paste was not deleted."}`,
+			wantErr:  ErrInvalidDeleteToken,
+		},
+		{
+			name:     "Unknown paste",
+			response: `{"status":1,"message":"Paste does not exist, has expired or has been deleted."}`,
+			wantErr:  ErrPasteNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						assert.Equal(t, http.MethodPost, r.Method)
+						assert.Equal(t, "JSONHttpRequest", r.Header.Get("X-Requested-With"))
+
+						body, err := io.ReadAll(r.Body)
+						require.NoError(t, err)
+
+						var req deletePasteRequest
+						require.NoError(t, json.Unmarshal(body, &req))
+						assert.Equal(t, "f468483c313401e8", req.PasteID)
+						assert.Equal(t, "token", req.DeleteToken)
+
+						_, _ = io.WriteString(w, tt.response)
+					},
+				),
+			)
+			defer server.Close()
+
+			endpoint, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			paste := "f468483c313401e8"
+			if tt.byURL {
+				paste = server.URL + "/?f468483c313401e8#mk"
+			}
+
+			client := NewClient(*endpoint)
+			err = client.DeletePaste(context.Background(), paste, "token")
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_ForeignHostCredentials(t *testing.T) {
+	type received struct {
+		authorization string
+		token         string
+	}
+
+	newServer := func(requests *[]received) *httptest.Server {
+		return httptest.NewServer(
+			http.HandlerFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					*requests = append(*requests, received{r.Header.Get("Authorization"), r.Header.Get("X-Token")})
+					_, _ = w.Write([]byte(`{"status":0,"id":"f468483c313401e8","url":"/?f468483c313401e8"}`))
+				},
+			),
+		)
+	}
+
+	var own, foreign []received
+
+	ownServer := newServer(&own)
+	defer ownServer.Close()
+
+	foreignServer := newServer(&foreign)
+	defer foreignServer.Close()
+
+	endpoint, err := url.Parse(ownServer.URL + "/")
+	require.NoError(t, err)
+
+	client := NewClient(
+		*endpoint,
+		WithBasicAuth("user", "secret"),
+		WithCustomHeaderField("X-Token", "secret"),
+	)
+
+	masterKey := base58.Encode(bytes.Repeat([]byte{1}, 32))
+
+	for _, base := range []string{ownServer.URL, foreignServer.URL} {
+		pasteURL, err := url.Parse(base + "/?f468483c313401e8#" + masterKey)
+		require.NoError(t, err)
+
+		_ = client.DeletePaste(context.Background(), pasteURL.String(), "token")
+		_, _ = client.CreateComment(context.Background(), *pasteURL, "", "", "hi", CreateCommentOptions{})
+	}
+
+	require.Len(t, own, 2)
+	for _, r := range own {
+		assert.NotEmpty(t, r.authorization)
+		assert.Equal(t, "secret", r.token)
+	}
+
+	require.Len(t, foreign, 2)
+	for _, r := range foreign {
+		assert.Empty(t, r.authorization)
+		assert.Empty(t, r.token)
+	}
+}
+
+func TestClient_CreateComment(t *testing.T) {
+	masterKey := []byte("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name         string
+		parentID     string
+		nickname     string
+		password     []byte
+		wantParentID string
+	}{
+		{
+			name:         "Reply to the paste",
+			nickname:     "alice",
+			wantParentID: "f468483c313401e8",
+		},
+		{
+			name:         "Reply to a comment",
+			parentID:     "a1b2c3d4e5f60718",
+			wantParentID: "a1b2c3d4e5f60718",
+		},
+		{
+			name:         "Password protected paste",
+			nickname:     "bob",
+			password:     []byte("s3cr3t"),
+			wantParentID: "f468483c313401e8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						var req createCommentRequest
+						require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+						assert.Equal(t, 2, req.V)
+						assert.Equal(t, "f468483c313401e8", req.PasteID)
+						assert.Equal(t, tt.wantParentID, req.ParentID)
+
+						authData, err := json.Marshal(req.AData)
+						require.NoError(t, err)
+
+						key := append(append([]byte{}, masterKey...), tt.password...)
+						data, err := decrypt(key, req.CT, authData, req.AData, DefaultDecryptPolicy(), discardLogger)
+						require.NoError(t, err)
+
+						var message commentMessage
+						require.NoError(t, json.Unmarshal(data, &message))
+						assert.Equal(t, "hello", message.Comment)
+						assert.Equal(t, tt.nickname, message.Nickname)
+
+						_, _ = io.WriteString(w, `{"status":0,"id":"0011223344556677"}`)
+					},
+				),
+			)
+			defer server.Close()
+
+			pasteURL, err := url.Parse(server.URL + "/?f468483c313401e8#" + base58.Encode(masterKey))
+			require.NoError(t, err)
+
+			client := NewClient(*pasteURL)
+			result, err := client.CreateComment(
+				context.Background(),
+				*pasteURL,
+				tt.parentID,
+				tt.nickname,
+				"hello",
+				CreateCommentOptions{
+					Compress: CompressionAlgorithmGZip,
+					Password: tt.password,
+				},
+			)
+			require.NoError(t, err)
+			assert.Equal(t, "0011223344556677", result.CommentID)
+			assert.Equal(t, tt.wantParentID, result.ParentID)
+		})
+	}
+}
+
+func TestClient_DecryptComments(t *testing.T) {
+	masterKey := []byte("0123456789abcdef0123456789abcdef")
+
+	newComment := func(t *testing.T, id string, key []byte) showPasteResponseComment {
+		spec, err := newSpec(CompressionAlgorithmGZip)
+		require.NoError(t, err)
+		spec.Iterations = 1000
+
+		authData, err := json.Marshal(spec)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(&commentMessage{Comment: "comment " + id, Nickname: id})
+		require.NoError(t, err)
+
+		ct, err := encrypt(key, data, authData, spec, discardLogger)
+		require.NoError(t, err)
+
+		return showPasteResponseComment{ID: id, PasteID: "paste", ParentID: "paste", V: 2, CT: ct, AData: spec}
+	}
+
+	var encrypted []showPasteResponseComment
+	for i := range 20 {
+		encrypted = append(encrypted, newComment(t, fmt.Sprintf("%02d", i), masterKey))
+	}
+
+	for _, workers := range []int{0, 1, 4, 64} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			client := NewClient(
+				url.URL{},
+				WithCommentDecryptionWorkers(workers),
+				WithDecryptPolicy(DecryptPolicy{}),
+			)
+
+			comments, err := client.decryptComments(context.Background(), masterKey, encrypted)
+			require.NoError(t, err)
+			require.Len(t, comments, len(encrypted))
+
+			for i, comment := range comments {
+				assert.Equal(t, encrypted[i].ID, comment.CommentID)
+				assert.Equal(t, "comment "+encrypted[i].ID, comment.Text)
+			}
+		})
+	}
+
+	t.Run("Per comment errors", func(t *testing.T) {
+		broken := append([]showPasteResponseComment{}, encrypted[:4]...)
+		broken[1] = newComment(t, "bad1", []byte("wrong"))
+		broken[3] = newComment(t, "bad3", []byte("wrong"))
+
+		client := NewClient(
+			url.URL{},
+			WithCommentDecryptionWorkers(2),
+			WithDecryptPolicy(DecryptPolicy{}),
+		)
+
+		comments, err := client.decryptComments(context.Background(), masterKey, broken)
+		require.NoError(t, err)
+		require.Len(t, comments, 4)
+
+		for _, i := range []int{0, 2} {
+			assert.NoError(t, comments[i].Err)
+			assert.Equal(t, "comment "+broken[i].ID, comments[i].Text)
+		}
+
+		for _, i := range []int{1, 3} {
+			require.ErrorIs(t, comments[i].Err, ErrDecryptionFailed)
+			assert.Contains(t, comments[i].Err.Error(), fmt.Sprintf("comment (#%d)", i))
+			assert.Equal(t, broken[i].ID, comments[i].CommentID)
+			assert.Empty(t, comments[i].Text)
+		}
+	})
+
+	t.Run("Comment iterations above the policy", func(t *testing.T) {
+		client := NewClient(
+			url.URL{},
+			WithDecryptPolicy(DecryptPolicy{MaxCommentIterations: 19 * 1000}),
+		)
+
+		_, err := client.decryptComments(context.Background(), masterKey, encrypted)
+		require.ErrorIs(t, err, ErrPolicyViolation)
+
+		var policyErr *PolicyViolationError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, "comment iterations", policyErr.Parameter)
+		assert.Equal(t, int64(20*1000), policyErr.Value)
+
+		client = NewClient(
+			url.URL{},
+			WithDecryptPolicy(DecryptPolicy{MaxCommentIterations: 20 * 1000}),
+		)
+
+		comments, err := client.decryptComments(context.Background(), masterKey, encrypted)
+		require.NoError(t, err)
+		require.Len(t, comments, len(encrypted))
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		client := NewClient(url.URL{})
+
+		_, err := client.decryptComments(ctx, masterKey, encrypted)
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClient_ShowPaste_BrokenComment(t *testing.T) {
+	encryptedPaste, masterKey, err := Seal(Paste{Data: []byte("hello")}, SealOptions{OpenDiscussion: true})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(
+					map[string]any{
+						"status":        0,
+						"id":            "f468483c313401e8",
+						"v":             encryptedPaste.V,
+						"adata":         encryptedPaste.AData,
+						"ct":            encryptedPaste.CT,
+						"comment_count": 1,
+						"comments": []map[string]any{
+							{
+								"id":       "0123456789abcdef",
+								"pasteid":  "f468483c313401e8",
+								"parentid": "f468483c313401e8",
+								"v":        2,
+								"ct":       "Z2FyYmFnZQ==",
+								"adata":    encryptedPaste.AData.Spec,
+							},
+						},
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	pasteURL, err := url.Parse(server.URL + "/?f468483c313401e8#" + masterKey.String())
+	require.NoError(t, err)
+
+	client := NewClient(*pasteURL)
+
+	result, err := client.ShowPaste(context.Background(), *pasteURL, ShowPasteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), result.Paste.Data)
+	require.Len(t, result.Comments, 1)
+	assert.Equal(t, "0123456789abcdef", result.Comments[0].CommentID)
+	assert.ErrorIs(t, result.Comments[0].Err, ErrDecryptionFailed)
+}
+
+func TestClient_ShowPaste_PromptPassword(t *testing.T) {
+	encryptedPaste, masterKey, err := Seal(
+		Paste{Data: []byte("hello")},
+		SealOptions{
+			Expire:           Expire1Day,
+			BurnAfterReading: true,
+			Password:         []byte("s3cr3t"),
+		},
+	)
+	require.NoError(t, err)
+
+	fetches := 0
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				_ = json.NewEncoder(w).Encode(
+					map[string]any{
+						"status": 0,
+						"id":     "f468483c313401e8",
+						"v":      encryptedPaste.V,
+						"adata":  encryptedPaste.AData,
+						"ct":     encryptedPaste.CT,
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	pasteURL, err := url.Parse(server.URL + "/?f468483c313401e8#-" + masterKey.String())
+	require.NoError(t, err)
+
+	client := NewClient(*pasteURL)
+
+	t.Run("retried without fetching again", func(t *testing.T) {
+		fetches = 0
+		passwords := [][]byte{[]byte("wrong"), []byte("s3cr3t")}
+		prompts := 0
+
+		result, err := client.ShowPaste(
+			context.Background(),
+			*pasteURL,
+			ShowPasteOptions{
+				ConfirmBurn: true,
+				PromptPassword: func(ctx context.Context) ([]byte, error) {
+					password := passwords[prompts]
+					prompts++
+					return password, nil
+				},
+			},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("hello"), result.Paste.Data)
+		assert.Equal(t, 2, prompts)
+		assert.Equal(t, 1, fetches)
+	})
+
+	t.Run("prompt error stops the attempts", func(t *testing.T) {
+		_, err := client.ShowPaste(
+			context.Background(),
+			*pasteURL,
+			ShowPasteOptions{
+				ConfirmBurn: true,
+				PromptPassword: func(ctx context.Context) ([]byte, error) {
+					return nil, ErrDecryptionFailed
+				},
+			},
+		)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+
+	t.Run("without prompt", func(t *testing.T) {
+		_, err := client.ShowPaste(context.Background(), *pasteURL, ShowPasteOptions{ConfirmBurn: true})
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+}
+
+func TestClient_FetchEnvelope(t *testing.T) {
+	encryptedPaste, masterKey, err := Seal(
+		Paste{Data: []byte("hello")},
+		SealOptions{
+			Formatter:        FormatterMarkdown,
+			Expire:           Expire1Day,
+			BurnAfterReading: true,
+			Compress:         CompressionAlgorithmGZip,
+		},
+	)
+	require.NoError(t, err)
+
+	fetches := 0
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				fetches++
+				assert.Equal(t, "f468483c313401e8", r.URL.RawQuery)
+				_ = json.NewEncoder(w).Encode(
+					map[string]any{
+						"status":        0,
+						"id":            "f468483c313401e8",
+						"v":             encryptedPaste.V,
+						"adata":         encryptedPaste.AData,
+						"ct":            encryptedPaste.CT,
+						"meta":          map[string]int{"created": 1700000000, "time_to_live": 3600},
+						"comment_count": 2,
+					},
+				)
+			},
+		),
+	)
+	defer server.Close()
+
+	pasteURL, err := url.Parse(server.URL + "/?f468483c313401e8")
+	require.NoError(t, err)
+
+	client := NewClient(*pasteURL)
+
+	t.Run("without the key", func(t *testing.T) {
+		envelope, err := client.FetchEnvelope(context.Background(), *pasteURL, FetchEnvelopeOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "f468483c313401e8", envelope.PasteID)
+		assert.Equal(t, time.Unix(1700000000, 0), envelope.Created)
+		assert.Equal(t, time.Hour, envelope.TimeToLive)
+		assert.Equal(t, 2, envelope.CommentCount)
+		assert.Equal(t, "markdown", envelope.EncryptedPaste.AData.Formatter)
+		assert.True(t, envelope.EncryptedPaste.AData.BurnAfterReading)
+		assert.Equal(t, CompressionAlgorithmGZip, envelope.EncryptedPaste.AData.Spec.Compression)
+
+		paste, err := Open(envelope.EncryptedPaste, masterKey, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("hello"), paste.Data)
+	})
+
+	t.Run("burn after reading not confirmed", func(t *testing.T) {
+		fetches = 0
+		burnURL := *pasteURL
+		burnURL.Fragment = "-" + masterKey.String()
+
+		_, err := client.FetchEnvelope(context.Background(), burnURL, FetchEnvelopeOptions{})
+		require.ErrorIs(t, err, ErrBurnNotConfirmed)
+		assert.Equal(t, 0, fetches)
+
+		_, err = client.FetchEnvelope(context.Background(), burnURL, FetchEnvelopeOptions{ConfirmBurn: true})
+		require.NoError(t, err)
+		assert.Equal(t, 1, fetches)
+	})
+}
+
+func TestClient_OversizedResponse(t *testing.T) {
+	// The server streams a cipher text far larger than the policy
+	// allows, the client must stop reading once the limit is reached.
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.WriteString(w, `{"status":0,"id":"f468483c313401e8","ct":"`)
+				chunk := strings.Repeat("A", 64<<10)
+				for range 1024 {
+					if _, err := io.WriteString(w, chunk); err != nil {
+						return
+					}
+				}
+				_, _ = io.WriteString(w, `"}`)
+			},
+		),
+	)
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+
+	policy := DefaultDecryptPolicy()
+	policy.MaxCipherTextSize = 1024
+
+	client := NewClient(*endpoint, WithDecryptPolicy(policy))
+
+	pasteURL := *endpoint
+	pasteURL.RawQuery = "f468483c313401e8"
+
+	_, err = client.FetchEnvelope(context.Background(), pasteURL, FetchEnvelopeOptions{})
+	require.ErrorIs(t, err, ErrPolicyViolation)
+
+	var policyErr *PolicyViolationError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, "response size", policyErr.Parameter)
+	assert.Equal(t, policy.maxResponseSize()+1, policyErr.Value)
+}
+
+func TestClient_HTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				_, _ = io.WriteString(w, `{"status":0,"id":"f468483c313401e8"}`)
+			},
+		),
+	)
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(server.Certificate())
+
+	var calls []string
+	middleware := func(name string) func(http.RoundTripper) http.RoundTripper {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(
+				func(req *http.Request) (*http.Response, error) {
+					calls = append(calls, name)
+					req.Header.Set("Authorization", "Bearer token")
+					return next.RoundTrip(req)
+				},
+			)
+		}
+	}
+
+	t.Run("Middlewares with TLS config", func(t *testing.T) {
+		calls = nil
+
+		client := NewClient(
+			*endpoint,
+			WithTLSConfig(&tls.Config{RootCAs: certPool}),
+			WithTransportMiddleware(middleware("first")),
+			WithTransportMiddleware(middleware("second")),
+		)
+		defer func() { _ = client.Close() }()
+
+		require.NoError(t, client.DeletePaste(context.Background(), "f468483c313401e8", "token"))
+		assert.Equal(t, []string{"first", "second"}, calls)
+	})
+
+	t.Run("Custom client with TLS config", func(t *testing.T) {
+		calls = nil
+
+		transport := &http.Transport{}
+		httpClient := &http.Client{Transport: transport}
+
+		client := NewClient(
+			*endpoint,
+			WithHTTPClient(httpClient),
+			WithTLSConfig(&tls.Config{RootCAs: certPool}),
+			WithTransportMiddleware(middleware("auth")),
+		)
+		defer func() { _ = client.Close() }()
+
+		require.NoError(t, client.DeletePaste(context.Background(), "f468483c313401e8", "token"))
+		assert.Equal(t, []string{"auth"}, calls)
+		assert.Same(t, transport, httpClient.Transport)
+		if transport.TLSClientConfig != nil {
+			assert.Nil(t, transport.TLSClientConfig.RootCAs)
+		}
+	})
+
+	t.Run("Custom client with its own transport", func(t *testing.T) {
+		calls = nil
+
+		httpClient := server.Client()
+		client := NewClient(
+			*endpoint,
+			WithHTTPClient(httpClient),
+			WithTransportMiddleware(middleware("auth")),
+		)
+		defer func() { _ = client.Close() }()
+
+		require.NoError(t, client.DeletePaste(context.Background(), "f468483c313401e8", "token"))
+		assert.Equal(t, []string{"auth"}, calls)
+		assert.Same(t, server.Client().Transport, client.transport)
+		assert.False(t, client.ownsTransport)
+	})
+
+	t.Run("Custom client with a wrapped transport and TLS config", func(t *testing.T) {
+		httpClient := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+
+		// The options cannot be applied, every request fails before
+		// reaching the server.
+		calls = nil
+
+		client := NewClient(
+			*endpoint,
+			WithHTTPClient(httpClient),
+			WithTLSConfig(&tls.Config{RootCAs: certPool}),
+			WithTransportMiddleware(middleware("auth")),
+		)
+
+		err := client.DeletePaste(context.Background(), "f468483c313401e8", "token")
+		require.ErrorContains(t, err, "cannot apply TLS config or proxy url")
+		assert.Empty(t, calls)
+
+		client = NewClient(
+			*endpoint,
+			WithHTTPClient(httpClient),
+			WithProxyURL(url.URL{Scheme: "http", Host: "127.0.0.1:3128"}),
+		)
+
+		err = client.DeletePaste(context.Background(), "f468483c313401e8", "token")
+		require.ErrorContains(t, err, "cannot apply TLS config or proxy url")
+	})
+}
+
+type closeCounter struct {
+	http.RoundTripper
+	closed int
+}
+
+func (c *closeCounter) CloseIdleConnections() {
+	c.closed++
+}
+
+func TestClient_Close(t *testing.T) {
+	t.Run("Shared transport", func(t *testing.T) {
+		transport := &closeCounter{RoundTripper: http.DefaultTransport}
+
+		client := NewClient(url.URL{}, WithHTTPClient(&http.Client{Transport: transport}))
+
+		require.NoError(t, client.Close())
+		assert.Equal(t, 0, transport.closed)
+	})
+
+	t.Run("Default transport", func(t *testing.T) {
+		client := NewClient(url.URL{}, WithHTTPClient(&http.Client{}))
+
+		assert.Same(t, http.DefaultTransport, client.transport)
+		assert.False(t, client.ownsTransport)
+		require.NoError(t, client.Close())
+	})
+
+	t.Run("Cloned transport", func(t *testing.T) {
+		client := NewClient(
+			url.URL{},
+			WithHTTPClient(&http.Client{}),
+			WithTLSConfig(&tls.Config{}),
+		)
+
+		assert.NotSame(t, http.DefaultTransport, client.transport)
+		assert.True(t, client.ownsTransport)
+		require.NoError(t, client.Close())
+	})
+
+	t.Run("Pooled transport", func(t *testing.T) {
+		client := NewClient(url.URL{})
+
+		assert.True(t, client.ownsTransport)
+		require.NoError(t, client.Close())
+	})
+}
@@ -18,48 +18,106 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+
+	"go.gearno.de/privatebin/v2"
 )
 
 type (
+	// ExpireCfg is an expire option read from the configuration. Unlike
+	// privatebin.Expire, it also accepts a Go duration (e.g. "36h")
+	// rounded to the nearest option, like the --expire flag.
+	ExpireCfg privatebin.Expire
+
 	AuthCfg struct {
 		Username string `json:"username"`
-		Password string `json:"password"`
+		Password Secret `json:"password"`
 	}
 
 	BinCfg struct {
-		Name              string            `json:"name"`
-		Host              string            `json:"host"`
-		Auth              AuthCfg           `json:"auth"`
-		Expire            string            `json:"expire"`
-		OpenDiscussion    *bool             `json:"open-discussion"`
-		BurnAfterReading  *bool             `json:"burn-after-reading"`
-		GZip              *bool             `json:"gzip"`
-		SkipTLSVerify     *bool             `json:"skip-tls-verify"`
-		Formatter         string            `json:"formatter"`
-		Proxy             string            `json:"proxy"`
-		ExtraHeaderFields map[string]string `json:"extra-header-fields"`
+		Name              string               `json:"name"`
+		Host              string               `json:"host"`
+		Auth              AuthCfg              `json:"auth"`
+		Expire            ExpireCfg            `json:"expire"`
+		OpenDiscussion    *bool                `json:"open-discussion"`
+		BurnAfterReading  *bool                `json:"burn-after-reading"`
+		GZip              *bool                `json:"gzip"`
+		SkipTLSVerify     *bool                `json:"skip-tls-verify"`
+		CAFile            string               `json:"ca-file"`
+		ClientCert        string               `json:"client-cert"`
+		ClientKey         string               `json:"client-key"`
+		MinTLSVersion     string               `json:"min-tls-version"`
+		Pins              []string             `json:"pins"`
+		Formatter         privatebin.Formatter `json:"formatter"`
+		Proxy             string               `json:"proxy"`
+		Retries           *int                 `json:"retries"`
+		History           *bool                `json:"history"`
+		HistoryWithoutKey *bool                `json:"history-without-key"`
+		ExtraHeaderFields map[string]Secret    `json:"extra-header-fields"`
 	}
 
 	Cfg struct {
-		Bin               []BinCfg          `json:"bin"`
-		Expire            string            `json:"expire"`
-		OpenDiscussion    bool              `json:"open-discussion"`
-		BurnAfterReading  bool              `json:"burn-after-reading"`
-		GZip              bool              `json:"gzip"`
-		SkipTLSVerify     bool              `json:"skip-tls-verify"`
-		Formatter         string            `json:"formatter"`
-		Proxy             string            `json:"proxy"`
-		ExtraHeaderFields map[string]string `json:"extra-header-fields"`
+		Bin               []BinCfg             `json:"bin"`
+		Expire            ExpireCfg            `json:"expire"`
+		OpenDiscussion    bool                 `json:"open-discussion"`
+		BurnAfterReading  bool                 `json:"burn-after-reading"`
+		GZip              bool                 `json:"gzip"`
+		SkipTLSVerify     bool                 `json:"skip-tls-verify"`
+		CAFile            string               `json:"ca-file"`
+		ClientCert        string               `json:"client-cert"`
+		ClientKey         string               `json:"client-key"`
+		MinTLSVersion     string               `json:"min-tls-version"`
+		Pins              []string             `json:"pins"`
+		Formatter         privatebin.Formatter `json:"formatter"`
+		Proxy             string               `json:"proxy"`
+		Retries           int                  `json:"retries"`
+		History           bool                 `json:"history"`
+		HistoryWithoutKey bool                 `json:"history-without-key"`
+		ExtraHeaderFields map[string]Secret    `json:"extra-header-fields"`
 	}
 )
 
+// MarshalJSON encodes the option name, an empty string when unset.
+func (e ExpireCfg) MarshalJSON() ([]byte, error) {
+	return privatebin.Expire(e).MarshalJSON()
+}
+
+// UnmarshalJSON decodes an option name or a positive Go duration, an
+// empty string leaves the option unset.
+func (e *ExpireCfg) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("cannot decode expire: %w", err)
+	}
+
+	if s == "" {
+		*e = ExpireCfg(privatebin.ExpireUnknown)
+		return nil
+	}
+
+	expire, err := privatebin.ParseExpire(s)
+	if err != nil {
+		d, durationErr := time.ParseDuration(s)
+		if durationErr != nil || d <= 0 {
+			return err
+		}
+
+		expire = privatebin.NearestExpire(d)
+	}
+
+	*e = ExpireCfg(expire)
+	return nil
+}
+
 func defaultConfig() *Cfg {
 	return &Cfg{
-		Expire:            "1day",
-		Formatter:         "plaintext",
+		Expire:            ExpireCfg(privatebin.Expire1Day),
+		Formatter:         privatebin.FormatterPlainText,
 		GZip:              true,
-		ExtraHeaderFields: make(map[string]string),
+		ExtraHeaderFields: make(map[string]Secret),
 	}
 }
 
@@ -73,6 +131,76 @@ func findBinCfg(cfg *Cfg, name string) (*BinCfg, error) {
 	return nil, fmt.Errorf("cannot find %q bin configuration", name)
 }
 
+// findBinCfgByURL returns the bin serving the paste instance URL, the one
+// with the longest path when several match, or nil.
+func findBinCfgByURL(cfg *Cfg, instance url.URL) *BinCfg {
+	var (
+		found   *BinCfg
+		longest = -1
+	)
+
+	for _, bin := range cfg.Bin {
+		if n, ok := matchBinHost(bin.Host, instance); ok && n > longest {
+			found, longest = &bin, n
+		}
+	}
+
+	return found
+}
+
+// matchBinHost reports whether the paste instance URL is served by the
+// bin host, and the length of the matching path prefix. Hosts are
+// compared case-insensitively, without default ports nor trailing
+// slashes; hosts without a scheme are assumed to use https.
+func matchBinHost(host string, instance url.URL) (int, bool) {
+	if host == "" {
+		return 0, false
+	}
+
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return 0, false
+	}
+
+	if !strings.EqualFold(u.Scheme, instance.Scheme) ||
+		normalizeHostPort(u) != normalizeHostPort(&instance) {
+		return 0, false
+	}
+
+	prefix := strings.TrimSuffix(u.Path, "/") + "/"
+	path := instance.Path
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
+	if !strings.HasPrefix(path, prefix) {
+		return 0, false
+	}
+
+	return len(prefix), true
+}
+
+// normalizeHostPort returns the lowercase host of u, with the port only
+// when it is not the default one of the scheme.
+func normalizeHostPort(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+
+	switch {
+	case port == "":
+	case strings.EqualFold(u.Scheme, "http") && port == "80":
+	case strings.EqualFold(u.Scheme, "https") && port == "443":
+	default:
+		host += ":" + port
+	}
+
+	return host
+}
+
 func loadCfgFile(path string) (*Cfg, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -90,7 +218,7 @@ func loadCfgFile(path string) (*Cfg, error) {
 	}
 
 	for i, binCfg := range cfg.Bin {
-		if binCfg.Expire == "" {
+		if binCfg.Expire == ExpireCfg(privatebin.ExpireUnknown) {
 			binCfg.Expire = cfg.Expire
 		}
 
@@ -102,7 +230,7 @@ func loadCfgFile(path string) (*Cfg, error) {
 			binCfg.BurnAfterReading = &cfg.BurnAfterReading
 		}
 
-		if binCfg.Formatter == "" {
+		if binCfg.Formatter == privatebin.FormatterUnknown {
 			binCfg.Formatter = cfg.Formatter
 		}
 
@@ -114,10 +242,39 @@ func loadCfgFile(path string) (*Cfg, error) {
 			binCfg.SkipTLSVerify = &cfg.SkipTLSVerify
 		}
 
+		if binCfg.CAFile == "" {
+			binCfg.CAFile = cfg.CAFile
+		}
+
+		if binCfg.ClientCert == "" && binCfg.ClientKey == "" {
+			binCfg.ClientCert = cfg.ClientCert
+			binCfg.ClientKey = cfg.ClientKey
+		}
+
+		if binCfg.MinTLSVersion == "" {
+			binCfg.MinTLSVersion = cfg.MinTLSVersion
+		}
+
+		if binCfg.Pins == nil {
+			binCfg.Pins = cfg.Pins
+		}
+
 		if binCfg.Proxy == "" {
 			binCfg.Proxy = cfg.Proxy
 		}
 
+		if binCfg.Retries == nil {
+			binCfg.Retries = &cfg.Retries
+		}
+
+		if binCfg.History == nil {
+			binCfg.History = &cfg.History
+		}
+
+		if binCfg.HistoryWithoutKey == nil {
+			binCfg.HistoryWithoutKey = &cfg.HistoryWithoutKey
+		}
+
 		if binCfg.ExtraHeaderFields == nil {
 			binCfg.ExtraHeaderFields = cfg.ExtraHeaderFields
 		}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.gearno.de/privatebin/v2"
+)
+
+func TestExpireCfg_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    privatebin.Expire
+		wantErr bool
+	}{
+		{name: "option", input: `"1week"`, want: privatebin.Expire1Week},
+		{name: "never", input: `"never"`, want: privatebin.ExpireNever},
+		{name: "empty", input: `""`, want: privatebin.ExpireUnknown},
+		{name: "exact duration", input: `"1h"`, want: privatebin.Expire1Hour},
+		{name: "rounded duration", input: `"36h"`, want: privatebin.Expire1Day},
+		{name: "negative duration", input: `"-1h"`, wantErr: true},
+		{name: "zero duration", input: `"0s"`, wantErr: true},
+		{name: "invalid", input: `"2weeks"`, wantErr: true},
+		{name: "not a string", input: `3600`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e ExpireCfg
+			err := json.Unmarshal([]byte(tt.input), &e)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, privatebin.Expire(e))
+		})
+	}
+}
+
+func TestExpireCfg_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal(ExpireCfg(privatebin.Expire1Month))
+	require.NoError(t, err)
+	assert.JSONEq(t, `"1month"`, string(data))
+}
+
+func TestMatchBinHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     string
+		instance string
+		want     bool
+	}{
+		{name: "same host", host: "https://bin.example.com", instance: "https://bin.example.com/", want: true},
+		{name: "host without scheme", host: "bin.example.com", instance: "https://bin.example.com/", want: true},
+		{name: "host without scheme over http", host: "bin.example.com", instance: "http://bin.example.com/"},
+		{name: "different scheme", host: "http://bin.example.com", instance: "https://bin.example.com/"},
+		{name: "case insensitive", host: "HTTPS://Bin.Example.COM", instance: "https://bin.example.com/", want: true},
+		{name: "default https port", host: "https://bin.example.com:443", instance: "https://bin.example.com/", want: true},
+		{name: "default http port", host: "http://bin.example.com", instance: "http://bin.example.com:80/", want: true},
+		{name: "other port", host: "https://bin.example.com:8443", instance: "https://bin.example.com/"},
+		{name: "trailing slash", host: "https://bin.example.com/", instance: "https://bin.example.com", want: true},
+		{name: "path prefix", host: "https://example.com/bin", instance: "https://example.com/bin/", want: true},
+		{name: "path prefix with trailing slash", host: "https://example.com/bin/", instance: "https://example.com/bin", want: true},
+		{name: "nested path", host: "https://example.com/bin", instance: "https://example.com/bin/v2/", want: true},
+		{name: "path sharing a prefix", host: "https://example.com/bin", instance: "https://example.com/binary/"},
+		{name: "path outside the prefix", host: "https://example.com/bin", instance: "https://example.com/"},
+		{name: "host sharing a prefix", host: "https://bin.example.com", instance: "https://bin.example.com.evil/"},
+		{name: "subdomain", host: "https://example.com", instance: "https://bin.example.com/"},
+		{name: "empty host", host: "", instance: "https://bin.example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := url.Parse(tt.instance)
+			require.NoError(t, err)
+
+			_, ok := matchBinHost(tt.host, *instance)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+}
+
+func TestFindBinCfgByURL(t *testing.T) {
+	cfg := &Cfg{
+		Bin: []BinCfg{
+			{Name: "root", Host: "https://example.com"},
+			{Name: "bin", Host: "https://example.com/bin/"},
+			{Name: "other", Host: "https://other.example.com"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		instance string
+		want     string
+	}{
+		{name: "longest path wins", instance: "https://example.com/bin/", want: "bin"},
+		{name: "root path", instance: "https://example.com/", want: "root"},
+		{name: "path sharing a prefix", instance: "https://example.com/binary/", want: "root"},
+		{name: "other host", instance: "https://other.example.com/", want: "other"},
+		{name: "unknown host", instance: "https://example.com.evil/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := url.Parse(tt.instance)
+			require.NoError(t, err)
+
+			bin := findBinCfgByURL(cfg, *instance)
+			if tt.want == "" {
+				assert.Nil(t, bin)
+				return
+			}
+
+			require.NotNil(t, bin)
+			assert.Equal(t, tt.want, bin.Name)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.gearno.de/privatebin/v2"
+)
+
+// parseExpire parses an expire option, or a Go duration (e.g. "36h")
+// rounded to the nearest option.
+func parseExpire(value string) (privatebin.Expire, error) {
+	e, err := privatebin.ParseExpire(value)
+	if err == nil {
+		return e, nil
+	}
+
+	d, durationErr := time.ParseDuration(value)
+	if durationErr != nil {
+		return privatebin.ExpireUnknown, err
+	}
+
+	if d <= 0 {
+		return privatebin.ExpireUnknown, fmt.Errorf("invalid expire: %q, the duration must be positive", value)
+	}
+
+	return nearestExpire(d, value), nil
+}
+
+// parseExpireAt parses a RFC 3339 timestamp and returns the option
+// expiring the paste the closest to it.
+func parseExpireAt(value string, now time.Time) (privatebin.Expire, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return privatebin.ExpireUnknown, fmt.Errorf("cannot parse expire-at: %w", err)
+	}
+
+	if !t.After(now) {
+		return privatebin.ExpireUnknown, fmt.Errorf("invalid expire-at: %s is in the past", value)
+	}
+
+	return nearestExpire(t.Sub(now), value), nil
+}
+
+func nearestExpire(d time.Duration, value string) privatebin.Expire {
+	e := privatebin.NearestExpire(d)
+	if e.Duration() != d {
+		_, _ = fmt.Fprintf(os.Stderr, "expire %s rounded to %s\n", value, e)
+	}
+
+	return e
+}
@@ -0,0 +1,491 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.gearno.de/privatebin/v2"
+)
+
+const (
+	historyKeySize = 32
+
+	// historyLockTimeout bounds the wait for another run updating the
+	// history, a lock older than historyLockStale is left over by a run
+	// that was killed.
+	historyLockTimeout = 10 * time.Second
+	historyLockStale   = time.Minute
+	historyLockRetry   = 50 * time.Millisecond
+)
+
+var (
+	// historyAData binds the cipher text to the file format version.
+	historyAData = []byte("privatebin history v1")
+)
+
+type (
+	HistoryEntry struct {
+		ID               int       `json:"id"`
+		Bin              string    `json:"bin"`
+		PasteID          string    `json:"paste_id"`
+		PasteURL         string    `json:"paste_url"`
+		DeleteToken      string    `json:"delete_token"`
+		BurnAfterReading bool      `json:"burn_after_reading"`
+		Created          time.Time `json:"created"`
+
+		// Expire is the option sent to the instance, unset when the
+		// instance default was used.
+		Expire privatebin.Expire `json:"expire"`
+
+		// ExpiresAt is zero when the paste never expires or when the
+		// expire option is unset.
+		ExpiresAt time.Time `json:"expires_at,omitzero"`
+	}
+
+	History struct {
+		NextID  int            `json:"next_id"`
+		Entries []HistoryEntry `json:"entries"`
+	}
+
+	// HistoryStore keeps the history of the created pastes in a file
+	// encrypted with AES-256-GCM. The key is stored in its own file, in
+	// the configuration directory, so a copy of the data directory alone
+	// does not disclose the delete tokens.
+	HistoryStore struct {
+		path    string
+		keyPath string
+	}
+)
+
+func (e HistoryEntry) Expired(now time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !now.Before(e.ExpiresAt)
+}
+
+// Add appends the entry to the history with a new ID, and returns it.
+func (h *History) Add(entry HistoryEntry) HistoryEntry {
+	h.NextID++
+	entry.ID = h.NextID
+	h.Entries = append(h.Entries, entry)
+
+	return entry
+}
+
+func (h *History) Find(id int) (HistoryEntry, bool) {
+	for _, e := range h.Entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+
+	return HistoryEntry{}, false
+}
+
+func (h *History) Remove(id int) bool {
+	for i, e := range h.Entries {
+		if e.ID == id {
+			h.Entries = append(h.Entries[:i], h.Entries[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Prune removes the expired entries and returns how many were removed.
+func (h *History) Prune(now time.Time) int {
+	entries := h.Entries[:0]
+	for _, e := range h.Entries {
+		if !e.Expired(now) {
+			entries = append(entries, e)
+		}
+	}
+
+	n := len(h.Entries) - len(entries)
+	h.Entries = entries
+
+	return n
+}
+
+// NewHistoryStore returns the store located in $XDG_DATA_HOME/privatebin,
+// defaulting to ~/.local/share/privatebin, with its key in the user
+// configuration directory.
+func NewHistoryStore() (*HistoryStore, error) {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine data directory: %w", err)
+		}
+
+		dataDir = filepath.Join(home, ".local", "share")
+	}
+
+	cfgDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine configuration directory: %w", err)
+	}
+
+	return &HistoryStore{
+		path:    filepath.Join(dataDir, "privatebin", "history"),
+		keyPath: filepath.Join(cfgDir, "privatebin", "history.key"),
+	}, nil
+}
+
+// Load reads the history, it is empty when the file does not exist yet.
+func (s *HistoryStore) Load() (*History, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &History{}, nil
+		}
+
+		return nil, fmt.Errorf("cannot read history: %w", err)
+	}
+
+	key, err := s.key(false)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newHistoryAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("cannot decrypt history: file is truncated")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], historyAData)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt history, %s may not be the key of %s: %w", s.keyPath, s.path, err)
+	}
+
+	var h History
+	if err := json.Unmarshal(plaintext, &h); err != nil {
+		return nil, fmt.Errorf("cannot decode history: %w", err)
+	}
+
+	return &h, nil
+}
+
+// Update applies fn to the history and saves it. Concurrent runs are
+// serialized with a lock file, so none of their changes is lost.
+func (s *HistoryStore) Update(fn func(h *History) error) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	h, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(h); err != nil {
+		return err
+	}
+
+	return s.Save(h)
+}
+
+// lock creates the lock file of the history, waiting for the current
+// holder to release it.
+func (s *HistoryStore) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create history directory: %w", err)
+	}
+
+	path := s.path + ".lock"
+	deadline := time.Now().Add(historyLockTimeout)
+
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err == nil {
+			fi, err := f.Stat()
+			_ = f.Close()
+			if err != nil {
+				_ = os.Remove(path)
+				return nil, fmt.Errorf("cannot lock history: %w", err)
+			}
+
+			// The lock may have been taken over as stale meanwhile,
+			// only the file created here is removed.
+			return func() { removeHistoryLock(path, fi) }, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("cannot lock history: %w", err)
+		}
+
+		if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) > historyLockStale {
+			removeHistoryLock(path, fi)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("cannot lock history: %s is held by another run, remove it if no other privatebin command is running", path)
+		}
+
+		time.Sleep(historyLockRetry)
+	}
+}
+
+// removeHistoryLock removes the lock file when it is still the file
+// described by fi, same file and same modification time. The lock is
+// first moved aside, which is atomic, so a lock created by another run
+// in the meantime is put back instead of being removed.
+func removeHistoryLock(path string, fi os.FileInfo) {
+	aside := path + "." + rand.Text()
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+
+	moved, err := os.Stat(aside)
+	if err != nil || !os.SameFile(fi, moved) || !moved.ModTime().Equal(fi.ModTime()) {
+		// A link, unlike a rename, never replaces a lock created
+		// since.
+		_ = os.Link(aside, path)
+	}
+
+	_ = os.Remove(aside)
+}
+
+// Save encrypts and writes the history, replacing the file atomically.
+func (s *HistoryStore) Save(h *History) error {
+	plaintext, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("cannot encode history: %w", err)
+	}
+
+	key, err := s.key(true)
+	if err != nil {
+		return err
+	}
+
+	aead, err := newHistoryAEAD(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("cannot generate nonce: %w", err)
+	}
+
+	data := aead.Seal(nonce, nonce, plaintext, historyAData)
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("cannot create history directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*")
+	if err != nil {
+		return fmt.Errorf("cannot create history file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write history: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write history: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("cannot write history: %w", err)
+	}
+
+	return nil
+}
+
+// key reads the history key, generating it when create is set and the
+// key file does not exist.
+func (s *HistoryStore) key(create bool) ([]byte, error) {
+	key, err := os.ReadFile(s.keyPath)
+	switch {
+	case err == nil:
+		if len(key) != historyKeySize {
+			return nil, fmt.Errorf("invalid history key %s: expected %d bytes, got %d", s.keyPath, historyKeySize, len(key))
+		}
+
+		return key, nil
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("cannot read history key: %w", err)
+	case !create:
+		return nil, fmt.Errorf("cannot read history key: %s does not exist", s.keyPath)
+	}
+
+	key = make([]byte, historyKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("cannot generate history key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.keyPath), 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create history key directory: %w", err)
+	}
+
+	// O_EXCL makes concurrent runs agree on a single key.
+	f, err := os.OpenFile(s.keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return s.key(false)
+		}
+
+		return nil, fmt.Errorf("cannot create history key: %w", err)
+	}
+
+	if _, err := f.Write(key); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("cannot write history key: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("cannot write history key: %w", err)
+	}
+
+	return key, nil
+}
+
+func newHistoryAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create history cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create history cipher: %w", err)
+	}
+
+	return aead, nil
+}
+
+func parseHistoryID(s string) (int, error) {
+	id, err := strconv.Atoi(s)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid history id: %q", s)
+	}
+
+	return id, nil
+}
+
+func loadHistory() (*HistoryStore, *History, error) {
+	store, err := NewHistoryStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h, err := store.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return store, h, nil
+}
+
+func findHistoryEntry(s string) (*HistoryStore, HistoryEntry, error) {
+	id, err := parseHistoryID(s)
+	if err != nil {
+		return nil, HistoryEntry{}, err
+	}
+
+	store, h, err := loadHistory()
+	if err != nil {
+		return nil, HistoryEntry{}, err
+	}
+
+	entry, ok := h.Find(id)
+	if !ok {
+		return nil, HistoryEntry{}, fmt.Errorf("cannot find history entry %d", id)
+	}
+
+	return store, entry, nil
+}
+
+// forgetHistoryEntry removes the entry from the history, it is not an
+// error when another run removed it first.
+func forgetHistoryEntry(store *HistoryStore, id int) error {
+	return store.Update(
+		func(h *History) error {
+			h.Remove(id)
+			return nil
+		},
+	)
+}
+
+// recordHistory adds the created paste to the history. The master key is
+// left out of the URL when the bin has history-without-key set.
+func recordHistory(bin *BinCfg, result *privatebin.CreatePasteResult, opts privatebin.CreatePasteOptions) error {
+	store, err := NewHistoryStore()
+	if err != nil {
+		return err
+	}
+
+	ref := result.PasteRef
+	if *bin.HistoryWithoutKey {
+		ref.MasterKey = nil
+	}
+
+	now := time.Now()
+	entry := HistoryEntry{
+		Bin:              bin.Name,
+		PasteID:          result.PasteID,
+		PasteURL:         ref.String(),
+		DeleteToken:      result.DeleteToken,
+		BurnAfterReading: opts.BurnAfterReading,
+		Created:          now,
+		Expire:           opts.Expire,
+	}
+
+	// Unset expire values are replaced by the instance default, which
+	// cannot be known here.
+	if ttl := opts.Expire.Duration(); ttl > 0 {
+		entry.ExpiresAt = now.Add(ttl)
+	}
+
+	return store.Update(
+		func(h *History) error {
+			h.Add(entry)
+			return nil
+		},
+	)
+}
+
+// formatExpiresAt returns when the paste of the entry expires, "never"
+// or "unknown" when the expire option was left to the instance default.
+func formatExpiresAt(e HistoryEntry) string {
+	switch {
+	case !e.ExpiresAt.IsZero():
+		return e.ExpiresAt.Local().Format(time.DateTime)
+	case e.Expire == privatebin.ExpireNever:
+		return "never"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.gearno.de/privatebin/v2"
+)
+
+func newTestHistoryStore(t *testing.T) *HistoryStore {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	return &HistoryStore{
+		path:    filepath.Join(dir, "data", "history"),
+		keyPath: filepath.Join(dir, "config", "history.key"),
+	}
+}
+
+func TestHistoryStore_ConcurrentUpdate(t *testing.T) {
+	store := newTestHistoryStore(t)
+
+	const n = 20
+
+	var wg sync.WaitGroup
+	for range n {
+		wg.Go(func() {
+			err := store.Update(
+				func(h *History) error {
+					h.Add(HistoryEntry{PasteID: "paste"})
+					return nil
+				},
+			)
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	h, err := store.Load()
+	require.NoError(t, err)
+	assert.Len(t, h.Entries, n)
+	assert.Equal(t, n, h.NextID)
+
+	_, err = os.Stat(store.path + ".lock")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestHistoryStore_StaleLock(t *testing.T) {
+	store := newTestHistoryStore(t)
+	lockPath := store.path + ".lock"
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(lockPath), 0o700))
+	require.NoError(t, os.WriteFile(lockPath, nil, 0o600))
+
+	old := time.Now().Add(-2 * historyLockStale)
+	require.NoError(t, os.Chtimes(lockPath, old, old))
+
+	err := store.Update(
+		func(h *History) error {
+			h.Add(HistoryEntry{PasteID: "paste"})
+			return nil
+		},
+	)
+	require.NoError(t, err)
+
+	h, err := store.Load()
+	require.NoError(t, err)
+	assert.Len(t, h.Entries, 1)
+}
+
+func TestHistoryStore_LockTakenOver(t *testing.T) {
+	store := newTestHistoryStore(t)
+	lockPath := store.path + ".lock"
+
+	unlock, err := store.lock()
+	require.NoError(t, err)
+
+	// Another run considers the lock stale and takes it over before the
+	// first run releases it.
+	require.NoError(t, os.Remove(lockPath))
+	require.NoError(t, os.WriteFile(lockPath, nil, 0o600))
+
+	unlock()
+
+	_, err = os.Stat(lockPath)
+	assert.NoError(t, err, "the lock of the other run must be kept")
+
+	entries, err := os.ReadDir(filepath.Dir(lockPath))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestRemoveHistoryLock(t *testing.T) {
+	t.Run("still the stale lock", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "history.lock")
+		require.NoError(t, os.WriteFile(lockPath, nil, 0o600))
+
+		fi, err := os.Stat(lockPath)
+		require.NoError(t, err)
+
+		removeHistoryLock(lockPath, fi)
+
+		entries, err := os.ReadDir(filepath.Dir(lockPath))
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("replaced by another run", func(t *testing.T) {
+		dir := t.TempDir()
+		lockPath := filepath.Join(dir, "history.lock")
+		require.NoError(t, os.WriteFile(lockPath, nil, 0o600))
+
+		stale, err := os.Stat(lockPath)
+		require.NoError(t, err)
+
+		// Keep the stale file alive so its inode is not reused.
+		require.NoError(t, os.Rename(lockPath, filepath.Join(dir, "old")))
+		require.NoError(t, os.WriteFile(lockPath, []byte("new"), 0o600))
+
+		removeHistoryLock(lockPath, stale)
+
+		data, err := os.ReadFile(lockPath)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 2)
+	})
+
+	t.Run("touched since", func(t *testing.T) {
+		lockPath := filepath.Join(t.TempDir(), "history.lock")
+		require.NoError(t, os.WriteFile(lockPath, nil, 0o600))
+
+		old := time.Now().Add(-2 * historyLockStale)
+		require.NoError(t, os.Chtimes(lockPath, old, old))
+
+		stale, err := os.Stat(lockPath)
+		require.NoError(t, err)
+
+		now := time.Now()
+		require.NoError(t, os.Chtimes(lockPath, now, now))
+
+		removeHistoryLock(lockPath, stale)
+
+		_, err = os.Stat(lockPath)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRecordHistory_Expire(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", filepath.Join(t.TempDir(), "data"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(t.TempDir(), "config"))
+
+	bin := &BinCfg{Name: "work", HistoryWithoutKey: new(false)}
+	result := &privatebin.CreatePasteResult{
+		PasteID: "f468483c313401e8",
+		PasteRef: privatebin.PasteRef{
+			Instance: url.URL{Scheme: "https", Host: "bin.example.com", Path: "/"},
+			PasteID:  "f468483c313401e8",
+		},
+		DeleteToken: "token",
+	}
+
+	for _, expire := range []privatebin.Expire{
+		privatebin.ExpireUnknown,
+		privatebin.ExpireNever,
+		privatebin.Expire1Day,
+	} {
+		require.NoError(t, recordHistory(bin, result, privatebin.CreatePasteOptions{Expire: expire}))
+	}
+
+	store, err := NewHistoryStore()
+	require.NoError(t, err)
+
+	h, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, h.Entries, 3)
+
+	assert.Equal(t, privatebin.ExpireUnknown, h.Entries[0].Expire)
+	assert.True(t, h.Entries[0].ExpiresAt.IsZero())
+	assert.Equal(t, "unknown", formatExpiresAt(h.Entries[0]))
+
+	assert.Equal(t, privatebin.ExpireNever, h.Entries[1].Expire)
+	assert.True(t, h.Entries[1].ExpiresAt.IsZero())
+	assert.Equal(t, "never", formatExpiresAt(h.Entries[1]))
+
+	assert.Equal(t, privatebin.Expire1Day, h.Entries[2].Expire)
+	assert.WithinDuration(t, h.Entries[2].Created.Add(24*time.Hour), h.Entries[2].ExpiresAt, time.Second)
+}
+
+func TestFormatExpiresAt(t *testing.T) {
+	expiresAt := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry HistoryEntry
+		want  string
+	}{
+		{
+			name:  "Expiration date",
+			entry: HistoryEntry{Expire: privatebin.Expire1Day, ExpiresAt: expiresAt},
+			want:  expiresAt.Local().Format(time.DateTime),
+		},
+		{
+			name:  "Never",
+			entry: HistoryEntry{Expire: privatebin.ExpireNever},
+			want:  "never",
+		},
+		{
+			name:  "Instance default",
+			entry: HistoryEntry{},
+			want:  "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatExpiresAt(tt.entry))
+		})
+	}
+}
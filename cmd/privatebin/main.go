@@ -16,20 +16,27 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"go.gearno.de/privatebin/v2"
+	"go.gearno.de/privatebin/v2/server"
 )
 
 var (
@@ -41,7 +48,11 @@ var (
 	cfgPath           string
 	binName           string
 	extraHeaderFields []string
+	retries           int
+	verbose           bool
+	debug             bool
 	client            *privatebin.Client
+	cfg               *Cfg
 	binCfg            *BinCfg
 	output            string
 
@@ -51,22 +62,52 @@ var (
 	}
 
 	expire           string
+	expireAt         string
 	openDiscussion   bool
 	burnAfterReading bool
 	gzip             bool
 	formatter        string
 	password         string
-	filename         string
+	passwordPrompt   bool
+	passwordFile     string
+	passwordFD       int
+	generatePassword int
+	history          bool
+	filenames        []string
 	attachment       bool
-
-	insecure      bool
-	confirmBurn   bool
-	skipTLSVerify bool
-	proxy         string
+	maxSize          int64
+
+	insecure         bool
+	confirmBurn      bool
+	skipComments     bool
+	saveAttachments  string
+	attachmentStdout bool
+	skipTLSVerify    bool
+	proxy            string
+	caFile           string
+	clientCert       string
+	clientKey        string
+	minTLSVersion    string
+	pins             []string
 
 	force    bool
 	initHost string
 
+	deleteToken string
+
+	historyAll bool
+
+	replyTo  string
+	nickname string
+
+	serveListen        string
+	serveStorage       string
+	serveDataDir       string
+	serveDefaultExpire string
+	serveSizeLimit     int
+	serveDiscussion    bool
+	servePurgeInterval time.Duration
+
 	rootCmd = &cobra.Command{
 		Use:     "privatebin",
 		Version: fmt.Sprintf("%s-%s (%s)", version, commit, date),
@@ -89,15 +130,29 @@ var (
 				cfgPath = p
 			}
 
-			cfg, err := loadCfgFile(cfgPath)
+			var err error
+			cfg, err = loadCfgFile(cfgPath)
 			if err != nil {
 				if !errors.Is(err, os.ErrNotExist) {
 					return fmt.Errorf("cannot load configuration: %w", err)
 				}
 				cfg = defaultConfig()
+			} else if cfg.hasInlineSecrets() {
+				warnReadableByOthers(cfgPath, "configuration file")
 			}
 
 			binCfg, err = findBinCfg(cfg, binName)
+
+			// Commands reading a paste use the bin serving it, with its
+			// credentials and transport settings, unless --bin is given.
+			if _, ok := cmd.Annotations[pasteURLAnnotation]; ok && len(args) > 0 && !cmd.Flags().Changed("bin") {
+				if ref, parseErr := privatebin.ParsePasteURL(args[0]); parseErr == nil {
+					if bin := findBinCfgByURL(cfg, ref.Instance); bin != nil {
+						binCfg, err = bin, nil
+					}
+				}
+			}
+
 			if err != nil {
 				binCfg = &BinCfg{
 					Expire:            cfg.Expire,
@@ -106,137 +161,247 @@ var (
 					GZip:              &cfg.GZip,
 					Formatter:         cfg.Formatter,
 					SkipTLSVerify:     &cfg.SkipTLSVerify,
+					CAFile:            cfg.CAFile,
+					ClientCert:        cfg.ClientCert,
+					ClientKey:         cfg.ClientKey,
+					MinTLSVersion:     cfg.MinTLSVersion,
+					Pins:              cfg.Pins,
 					Proxy:             cfg.Proxy,
+					Retries:           &cfg.Retries,
+					History:           &cfg.History,
+					HistoryWithoutKey: &cfg.HistoryWithoutKey,
 					ExtraHeaderFields: cfg.ExtraHeaderFields,
 				}
 			}
 
-			clientOptions = append(
-				clientOptions,
-				privatebin.WithBasicAuth(
-					binCfg.Auth.Username,
-					binCfg.Auth.Password,
-				),
-			)
+			if verbose || debug {
+				level := slog.LevelInfo
+				if debug {
+					level = slog.LevelDebug
+				}
 
-			for k, v := range binCfg.ExtraHeaderFields {
-				clientOptions = append(
-					clientOptions,
-					privatebin.WithCustomHeaderField(k, v),
-				)
+				logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+				clientOptions = append(clientOptions, privatebin.WithLogger(logger))
 			}
 
-			for _, value := range extraHeaderFields {
-				parts := strings.SplitN(value, ":", 2)
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid header field format: '%s', expected 'key: value'", value)
-				}
+			return nil
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if client != nil {
+				return client.Close()
+			}
 
-				clientOptions = append(
-					clientOptions,
-					privatebin.WithCustomHeaderField(
-						strings.TrimSpace(parts[0]),
-						strings.TrimSpace(parts[1]),
-					),
-				)
+			return nil
+		},
+	}
+
+	showCmd = &cobra.Command{
+		Use:          "show",
+		Short:        "Show a paste",
+		SilenceUsage: true,
+		Annotations:  map[string]string{pasteURLAnnotation: ""},
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref, err := privatebin.ParsePasteURL(args[0])
+			if err != nil {
+				return err
 			}
 
-			if (binCfg.SkipTLSVerify != nil && *binCfg.SkipTLSVerify) || skipTLSVerify {
-				tlsConfig := &tls.Config{
-					InsecureSkipVerify: true,
-				}
+			if err := checkTrustedHost(ref.Instance); err != nil {
+				return err
+			}
 
-				clientOptions = append(
-					clientOptions,
-					privatebin.WithTLSConfig(tlsConfig),
-				)
+			if attachmentStdout && output != "" {
+				return fmt.Errorf("--attachment-stdout cannot be used with --output")
 			}
 
-			proxyAddr := binCfg.Proxy
-			if proxy != "" {
-				proxyAddr = proxy
+			pastePassword, err := readPassword(cmd, false)
+			if err != nil {
+				return err
 			}
 
-			if proxyAddr != "" {
-				proxyURL, err := url.Parse(proxyAddr)
-				if err != nil {
-					return fmt.Errorf("cannot parse proxy url %q: %w", proxyAddr, err)
-				}
+			if err := connect(cmd); err != nil {
+				return err
+			}
 
-				clientOptions = append(
-					clientOptions,
-					privatebin.WithProxyURL(*proxyURL),
-				)
+			options := privatebin.ShowPasteOptions{
+				Password:       pastePassword,
+				ConfirmBurn:    confirmBurn,
+				SkipComments:   skipComments,
+				PromptPassword: passwordRetry(pastePassword != nil),
 			}
 
-			host, err := url.Parse(binCfg.Host)
+			result, err := client.ShowPaste(ctx, ref.URL(), options)
 			if err != nil {
-				return fmt.Errorf("cannot parse %q bin %q host: %w", binCfg.Name, binCfg.Host, err)
+				return fmt.Errorf("cannot show paste: %w", err)
+			}
+
+			if saveAttachments != "" {
+				if err := saveAttachmentsTo(saveAttachments, result.Paste.Attachments, force); err != nil {
+					return err
+				}
+			}
+
+			if attachmentStdout {
+				if len(result.Paste.Attachments) != 1 {
+					return fmt.Errorf("--attachment-stdout requires a paste with exactly one attachment, got %d", len(result.Paste.Attachments))
+				}
+
+				a := result.Paste.Attachments[0]
+				_, _ = fmt.Fprintf(os.Stderr, "attachment %q (%s, %d bytes)\n", a.SafeName(), a.MimeType, len(a.Data))
+
+				if _, err := os.Stdout.Write(a.Data); err != nil {
+					return fmt.Errorf("cannot write attachment: %w", err)
+				}
+
+				return nil
 			}
 
-			client = privatebin.NewClient(*host, clientOptions...)
+			switch output {
+			case "":
+				_, _ = fmt.Fprintf(os.Stdout, "%s\n", result.Paste.Data)
+
+				if len(result.Comments) > 0 {
+					_, _ = fmt.Fprintf(os.Stdout, "\n--- %d comment(s) ---\n", len(result.Comments))
+					printCommentTree(os.Stdout, result.CommentTree(), 0)
+				}
+			case "json":
+				comments := commentTreeJSON(result.CommentTree())
+
+				var created *time.Time
+				if !result.Created.IsZero() {
+					created = &result.Created
+				}
+
+				_ = json.NewEncoder(os.Stdout).Encode(
+					map[string]any{
+						"paste_id":           result.PasteID,
+						"paste":              pasteJSON(result.Paste),
+						"created":            created,
+						"time_to_live":       int64(result.TimeToLive / time.Second),
+						"formatter":          result.Formatter,
+						"open_discussion":    result.OpenDiscussion,
+						"burn_after_reading": result.BurnAfterReading,
+						"comment_count":      result.CommentCount,
+						"comments":           comments,
+					},
+				)
+			}
 			return nil
 		},
 	}
 
-	showCmd = &cobra.Command{
-		Use:          "show",
-		Short:        "Show a paste",
+	inspectCmd = &cobra.Command{
+		Use:          "inspect <url>",
+		Short:        "Show the metadata of a paste without decrypting it",
 		SilenceUsage: true,
+		Annotations:  map[string]string{pasteURLAnnotation: ""},
 		Args:         cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			link, err := url.Parse(args[0])
+			ref, err := privatebin.ParsePasteURL(args[0])
 			if err != nil {
-				return fmt.Errorf("cannot parse paste url: %w", err)
+				return err
+			}
+
+			if err := checkTrustedHost(ref.Instance); err != nil {
+				return err
 			}
 
-			if link.Scheme+"://"+link.Host != strings.TrimRight(binCfg.Host, "/") {
-				if !insecure {
-					return fmt.Errorf("untrusted privatebin instance use --insecure flag or add it to the configuration")
+			// PrivateBin deletes burn after reading pastes when they are
+			// fetched, so the paste cannot be checked beforehand. Only a
+			// URL carrying the key with its "-" marker is known to point
+			// to one, the fetch is then confirmed before the request is
+			// sent.
+			confirm := confirmBurn
+			if ref.BurnAfterReading && !confirm {
+				ok, err := promptConfirm("The paste is burn after reading and will be deleted once inspected, continue?")
+				if err != nil {
+					return fmt.Errorf("cannot inspect paste: use --confirm-burn to fetch a burn after reading paste without a terminal: %w", privatebin.ErrBurnNotConfirmed)
+				}
+
+				if !ok {
+					return fmt.Errorf("cannot inspect paste: %w", privatebin.ErrBurnNotConfirmed)
 				}
+
+				confirm = true
 			}
 
-			options := privatebin.ShowPasteOptions{
-				Password:    []byte(password),
-				ConfirmBurn: confirmBurn,
+			if err := connect(cmd); err != nil {
+				return err
 			}
 
-			result, err := client.ShowPaste(ctx, *link, options)
+			envelope, err := client.FetchEnvelope(
+				ctx,
+				ref.URL(),
+				privatebin.FetchEnvelopeOptions{ConfirmBurn: confirm},
+			)
 			if err != nil {
-				return fmt.Errorf("cannot show paste: %w", err)
+				return fmt.Errorf("cannot inspect paste: %w", err)
+			}
+
+			cipherText, err := base64.StdEncoding.DecodeString(envelope.EncryptedPaste.CT)
+			if err != nil {
+				return fmt.Errorf("cannot inspect paste: cannot base64 decode cipher text: %w", err)
+			}
+
+			adata := envelope.EncryptedPaste.AData
+
+			if adata.BurnAfterReading && !ref.BurnAfterReading {
+				_, _ = fmt.Fprintln(os.Stderr, "warning: the paste is burn after reading, the instance may have deleted it")
+			}
+
+			var created any
+			if !envelope.Created.IsZero() {
+				created = envelope.Created.UTC().Format(time.RFC3339)
 			}
 
 			switch output {
 			case "":
-				_, _ = fmt.Fprintf(os.Stdout, "%s\n", result.Paste.Data)
-			case "json":
-				var comments []map[string]string
-				for _, comment := range result.Comments {
-					comments = append(
-						comments,
-						map[string]string{
-							"comment_id": comment.CommentID,
-							"paste_id":   comment.PasteID,
-							"parent_id":  comment.ParentID,
-							"nickname":   comment.Nickname,
-							"text":       comment.Text,
-						},
-					)
+				createdText := "unknown"
+				if !envelope.Created.IsZero() {
+					createdText = envelope.Created.Local().Format(time.DateTime)
+				}
+
+				expiresText := "never"
+				if envelope.TimeToLive > 0 {
+					expiresText = "in " + envelope.TimeToLive.String()
 				}
 
+				_, _ = fmt.Fprintf(os.Stdout, "paste id: %s\n", envelope.PasteID)
+				_, _ = fmt.Fprintf(os.Stdout, "created: %s\n", createdText)
+				_, _ = fmt.Fprintf(os.Stdout, "expires: %s\n", expiresText)
+				_, _ = fmt.Fprintf(os.Stdout, "burn after reading: %t\n", adata.BurnAfterReading)
+				_, _ = fmt.Fprintf(os.Stdout, "open discussion: %t\n", adata.OpenDiscussion)
+				_, _ = fmt.Fprintf(os.Stdout, "formatter: %s\n", adata.Formatter)
+				_, _ = fmt.Fprintf(os.Stdout, "compression: %s\n", adata.Spec.Compression)
+				_, _ = fmt.Fprintf(
+					os.Stdout,
+					"encryption: %s-%d-%s, %d bits tag, %d PBKDF2 iterations\n",
+					adata.Spec.Algorithm,
+					adata.Spec.KeySize,
+					adata.Spec.Mode,
+					adata.Spec.TagSize,
+					adata.Spec.Iterations,
+				)
+				_, _ = fmt.Fprintf(os.Stdout, "cipher text: %d bytes\n", len(cipherText))
+				_, _ = fmt.Fprintf(os.Stdout, "comments: %d\n", envelope.CommentCount)
+			case "json":
 				_ = json.NewEncoder(os.Stdout).Encode(
 					map[string]any{
-						"paste_id": result.PasteID,
-						"paste": map[string]string{
-							"attachment_name": result.Paste.AttachmentName,
-							"attachment":      base64.StdEncoding.EncodeToString(result.Paste.Attachment),
-							"data":            base64.StdEncoding.EncodeToString(result.Paste.Data),
-						},
-						"comment_count": result.CommentCount,
-						"comments":      comments,
+						"paste_id":           envelope.PasteID,
+						"created":            created,
+						"time_to_live":       int64(envelope.TimeToLive / time.Second),
+						"formatter":          adata.Formatter,
+						"open_discussion":    adata.OpenDiscussion,
+						"burn_after_reading": adata.BurnAfterReading,
+						"comment_count":      envelope.CommentCount,
+						"v":                  envelope.EncryptedPaste.V,
+						"adata":              adata,
+						"cipher_text_size":   len(cipherText),
 					},
 				)
 			}
+
 			return nil
 		},
 	}
@@ -252,7 +417,21 @@ var (
 			}
 
 			if cmd.Flags().Changed("expire") {
-				binCfg.Expire = expire
+				e, err := parseExpire(expire)
+				if err != nil {
+					return err
+				}
+
+				binCfg.Expire = ExpireCfg(e)
+			}
+
+			if cmd.Flags().Changed("expire-at") {
+				e, err := parseExpireAt(expireAt, time.Now())
+				if err != nil {
+					return err
+				}
+
+				binCfg.Expire = ExpireCfg(e)
 			}
 
 			if cmd.Flags().Changed("open-discussion") {
@@ -268,77 +447,208 @@ var (
 			}
 
 			if cmd.Flags().Changed("formatter") {
-				binCfg.Formatter = formatter
+				f, err := privatebin.ParseFormatter(formatter)
+				if err != nil {
+					return err
+				}
+
+				binCfg.Formatter = f
+			}
+
+			if cmd.Flags().Changed("history") {
+				binCfg.History = &history
+			}
+
+			// Several files can only be sent as attachments.
+			asAttachment := cmd.Flags().Changed("attachment") || len(filenames) > 1
+
+			if len(args) > 0 && !asAttachment {
+				return fmt.Errorf("positional message argument can only be used with --attachment flag")
 			}
 
 			var (
-				attachementName string
-				message         []byte
-				data            []byte
-				err             error
+				pastePassword []byte
+				err           error
 			)
 
-			if cmd.Flags().Changed("filename") {
-				file, err := os.Open(filename)
-				if err != nil {
-					return fmt.Errorf("cannot open %q file: %w", filename, err)
+			generated := cmd.Flags().Changed("generate-password")
+			if generated {
+				pastePassword, err = privatebin.GeneratePassword(generatePassword)
+			} else {
+				pastePassword, err = readPassword(cmd, true)
+			}
+			if err != nil {
+				return err
+			}
+
+			options := privatebin.CreatePasteOptions{
+				Formatter:        binCfg.Formatter,
+				Expire:           privatebin.Expire(binCfg.Expire),
+				OpenDiscussion:   *binCfg.OpenDiscussion,
+				BurnAfterReading: *binCfg.BurnAfterReading,
+				Password:         pastePassword,
+				Compress:         privatebin.CompressionAlgorithmNone,
+				MaxSize:          maxSize,
+			}
+
+			if *binCfg.GZip {
+				options.Compress = privatebin.CompressionAlgorithmGZip
+			}
+
+			if err := connect(cmd); err != nil {
+				return err
+			}
+
+			var result *privatebin.CreatePasteResult
+
+			if len(filenames) > 1 {
+				for _, filename := range filenames {
+					content, err := os.ReadFile(filename)
+					if err != nil {
+						return fmt.Errorf("cannot read %q file: %w", filename, err)
+					}
+
+					if maxSize > 0 && int64(len(content)) > maxSize {
+						return fmt.Errorf("cannot read %q file: %w", filename, privatebin.ErrPasteTooLarge)
+					}
+
+					options.Attachments = append(
+						options.Attachments,
+						privatebin.Attachment{Name: filepath.Base(filename), Data: content},
+					)
 				}
 
-				data, err = io.ReadAll(file)
-				if err != nil {
-					return fmt.Errorf("cannot read %q file: %w", filename, err)
+				// The positional message, if any, is the paste text.
+				var data []byte
+				if len(args) > 0 {
+					data = []byte(args[0])
+				}
+
+				result, err = client.CreatePaste(ctx, data, options)
+			} else {
+				// A single input is streamed rather than read in memory
+				// first, as it may be a large file.
+				input, name := os.Stdin, "stdin"
+				if len(filenames) == 1 {
+					input, err = os.Open(filenames[0])
+					if err != nil {
+						return fmt.Errorf("cannot read %q file: %w", filenames[0], err)
+					}
+					defer func() { _ = input.Close() }()
+
+					name = filepath.Base(filenames[0])
 				}
 
-				if cmd.Flags().Changed("attachment") {
-					attachementName = filepath.Base(filename)
+				if asAttachment {
+					options.AttachmentName = name
+					if len(args) > 0 {
+						options.Message = []byte(args[0])
+					}
 				}
+
+				result, err = client.CreatePasteFromReader(ctx, input, options)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot create the paste: %w", err)
+			}
+
+			// The paste exists at this point, failing to record it must
+			// not hide its URL.
+			if *binCfg.History {
+				if err := recordHistory(binCfg, result, options); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "warning: cannot record the paste in the history: %v\n", err)
+				}
+			}
+
+			switch output {
+			case "":
+				_, _ = fmt.Fprintf(os.Stdout, "%s\n", result.PasteRef.String())
+
+				// The password is meant for another channel, keep it
+				// out of the URL output.
+				if generated {
+					_, _ = fmt.Fprintf(os.Stderr, "password: %s\n", pastePassword)
+				}
+			case "json":
+				v := map[string]any{
+					"paste_id":     result.PasteID,
+					"paste_url":    result.PasteRef.String(),
+					"delete_token": result.DeleteToken,
+				}
+
+				if generated {
+					v["password"] = string(pastePassword)
+				}
+
+				_ = json.NewEncoder(os.Stdout).Encode(v)
+			}
+
+			return nil
+		},
+	}
+
+	commentCmd = &cobra.Command{
+		Use:          "comment <url> [text]",
+		Short:        "Comment a paste",
+		SilenceUsage: true,
+		Annotations:  map[string]string{pasteURLAnnotation: ""},
+		Args:         cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref, err := privatebin.ParsePasteURL(args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := checkTrustedHost(ref.Instance); err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("gzip") {
+				binCfg.GZip = &gzip
+			}
+
+			var text []byte
+			if len(args) > 1 {
+				text = []byte(args[1])
 			} else {
-				data, err = io.ReadAll(os.Stdin)
+				text, err = io.ReadAll(os.Stdin)
 				if err != nil {
 					return fmt.Errorf("cannot read stdin: %w", err)
 				}
+			}
 
-				if cmd.Flags().Changed("attachment") {
-					attachementName = "stdin"
-				}
+			pastePassword, err := readPassword(cmd, false)
+			if err != nil {
+				return err
 			}
 
-			if len(args) > 0 {
-				if !cmd.Flags().Changed("attachment") {
-					return fmt.Errorf("positional message argument can only be used with --attachment flag")
-				}
-				message = []byte(args[0])
+			if err := connect(cmd); err != nil {
+				return err
 			}
 
-			options := privatebin.CreatePasteOptions{
-				AttachmentName:   attachementName,
-				Message:          message,
-				Formatter:        binCfg.Formatter,
-				Expire:           binCfg.Expire,
-				OpenDiscussion:   *binCfg.OpenDiscussion,
-				BurnAfterReading: *binCfg.BurnAfterReading,
-				Password:         []byte(password),
-				Compress:         privatebin.CompressionAlgorithmNone,
+			options := privatebin.CreateCommentOptions{
+				Password: pastePassword,
+				Compress: privatebin.CompressionAlgorithmNone,
 			}
 
 			if *binCfg.GZip {
 				options.Compress = privatebin.CompressionAlgorithmGZip
 			}
 
-			result, err := client.CreatePaste(ctx, data, options)
+			result, err := client.CreateComment(ctx, ref.URL(), replyTo, nickname, string(text), options)
 			if err != nil {
-				return fmt.Errorf("cannot create the paste: %w", err)
+				return fmt.Errorf("cannot create the comment: %w", err)
 			}
 
 			switch output {
 			case "":
-				_, _ = fmt.Fprintf(os.Stdout, "%s\n", result.PasteURL.String())
+				_, _ = fmt.Fprintf(os.Stdout, "%s\n", result.CommentID)
 			case "json":
 				_ = json.NewEncoder(os.Stdout).Encode(
 					map[string]any{
-						"paste_id":     result.PasteID,
-						"paste_url":    result.PasteURL.String(),
-						"delete_token": result.DeleteToken,
+						"comment_id": result.CommentID,
+						"paste_id":   result.PasteID,
+						"parent_id":  result.ParentID,
 					},
 				)
 			}
@@ -347,6 +657,305 @@ var (
 		},
 	}
 
+	deleteCmd = &cobra.Command{
+		Use:          "delete [url]",
+		Short:        "Delete a paste",
+		SilenceUsage: true,
+		Annotations:  map[string]string{pasteURLAnnotation: ""},
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var pasteURL, token string
+
+			if len(args) > 0 {
+				pasteURL = args[0]
+				token = deleteToken
+			} else {
+				var createOutput struct {
+					PasteURL    string `json:"paste_url"`
+					DeleteToken string `json:"delete_token"`
+				}
+
+				if err := json.NewDecoder(os.Stdin).Decode(&createOutput); err != nil {
+					return fmt.Errorf("cannot decode create output from stdin: %w", err)
+				}
+
+				pasteURL = createOutput.PasteURL
+				token = createOutput.DeleteToken
+
+				if cmd.Flags().Changed("token") {
+					token = deleteToken
+				}
+			}
+
+			if token == "" {
+				return fmt.Errorf("missing delete token, use the --token flag")
+			}
+
+			ref, err := privatebin.ParsePasteURL(pasteURL)
+			if err != nil {
+				return err
+			}
+
+			if err := checkTrustedHost(ref.Instance); err != nil {
+				return err
+			}
+
+			if err := connect(cmd); err != nil {
+				return err
+			}
+
+			if err := client.DeletePaste(ctx, ref.String(), token); err != nil {
+				return fmt.Errorf("cannot delete the paste: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Manage the history of the created pastes",
+	}
+
+	historyListCmd = &cobra.Command{
+		Use:          "list",
+		Short:        "List the pastes of the history",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, h, err := loadHistory()
+			if err != nil {
+				return err
+			}
+
+			now := time.Now()
+			entries := make([]HistoryEntry, 0, len(h.Entries))
+			for _, e := range h.Entries {
+				if historyAll || !e.Expired(now) {
+					entries = append(entries, e)
+				}
+			}
+
+			switch output {
+			case "":
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				_, _ = fmt.Fprintln(w, "ID\tBIN\tCREATED\tEXPIRES\tURL")
+				for _, e := range entries {
+					_, _ = fmt.Fprintf(
+						w,
+						"%d\t%s\t%s\t%s\t%s\n",
+						e.ID,
+						e.Bin,
+						e.Created.Local().Format(time.DateTime),
+						formatExpiresAt(e),
+						e.PasteURL,
+					)
+				}
+				return w.Flush()
+			case "json":
+				_ = json.NewEncoder(os.Stdout).Encode(entries)
+			}
+
+			return nil
+		},
+	}
+
+	historyShowCmd = &cobra.Command{
+		Use:          "show <id>",
+		Short:        "Show a paste of the history",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, entry, err := findHistoryEntry(args[0])
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "":
+				_, _ = fmt.Fprintf(os.Stdout, "id: %d\n", entry.ID)
+				_, _ = fmt.Fprintf(os.Stdout, "bin: %s\n", entry.Bin)
+				_, _ = fmt.Fprintf(os.Stdout, "paste id: %s\n", entry.PasteID)
+				_, _ = fmt.Fprintf(os.Stdout, "url: %s\n", entry.PasteURL)
+				_, _ = fmt.Fprintf(os.Stdout, "delete token: %s\n", entry.DeleteToken)
+				_, _ = fmt.Fprintf(os.Stdout, "burn after reading: %t\n", entry.BurnAfterReading)
+				_, _ = fmt.Fprintf(os.Stdout, "created: %s\n", entry.Created.Local().Format(time.DateTime))
+				_, _ = fmt.Fprintf(os.Stdout, "expires: %s\n", formatExpiresAt(entry))
+			case "json":
+				_ = json.NewEncoder(os.Stdout).Encode(entry)
+			}
+
+			return nil
+		},
+	}
+
+	historyForgetCmd = &cobra.Command{
+		Use:          "forget <id>",
+		Short:        "Remove a paste from the history, without deleting it",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, entry, err := findHistoryEntry(args[0])
+			if err != nil {
+				return err
+			}
+
+			return forgetHistoryEntry(store, entry.ID)
+		},
+	}
+
+	historyPruneCmd = &cobra.Command{
+		Use:          "prune",
+		Short:        "Remove the expired pastes from the history",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := NewHistoryStore()
+			if err != nil {
+				return err
+			}
+
+			var n int
+			err = store.Update(
+				func(h *History) error {
+					n = h.Prune(time.Now())
+					return nil
+				},
+			)
+			if err != nil {
+				return err
+			}
+
+			if n == 0 {
+				return nil
+			}
+
+			_, _ = fmt.Fprintf(os.Stderr, "removed %d expired entries\n", n)
+
+			return nil
+		},
+	}
+
+	historyDeleteCmd = &cobra.Command{
+		Use:          "delete <id>",
+		Short:        "Delete a paste of the history from its instance",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, entry, err := findHistoryEntry(args[0])
+			if err != nil {
+				return err
+			}
+
+			ref, err := privatebin.ParsePasteURL(entry.PasteURL)
+			if err != nil {
+				return err
+			}
+
+			// The delete token is only sent with the credentials and
+			// transport settings of the bin serving the paste, never
+			// with the ones of another bin.
+			bin := findBinCfgByURL(cfg, ref.Instance)
+			if bin == nil {
+				return fmt.Errorf("cannot delete the paste: %s matches no configured bin", ref.Instance.String())
+			}
+
+			binClient, err := newClient(cmd, bin)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = binClient.Close() }()
+
+			if err := binClient.DeletePaste(ctx, ref.String(), entry.DeleteToken); err != nil {
+				// A paste already gone is deleted as far as the history
+				// is concerned.
+				if !errors.Is(err, privatebin.ErrPasteNotFound) {
+					return fmt.Errorf("cannot delete the paste: %w", err)
+				}
+			}
+
+			return forgetHistoryEntry(store, entry.ID)
+		},
+	}
+
+	serveCmd = &cobra.Command{
+		Use:          "serve",
+		Short:        "Run a PrivateBin compatible server",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var storage server.Storage
+
+			switch serveStorage {
+			case "memory":
+				storage = server.NewMemoryStorage()
+			case "filesystem":
+				fs, err := server.NewFilesystemStorage(serveDataDir)
+				if err != nil {
+					return fmt.Errorf("cannot create filesystem storage: %w", err)
+				}
+				storage = fs
+			default:
+				return fmt.Errorf("invalid storage: %q, valid options are 'filesystem', 'memory'", serveStorage)
+			}
+
+			defaultExpire, err := privatebin.ParseExpire(serveDefaultExpire)
+			if err != nil {
+				return fmt.Errorf("invalid default expire: %w", err)
+			}
+
+			handler := server.New(
+				storage,
+				server.WithDefaultExpire(defaultExpire),
+				server.WithSizeLimit(serveSizeLimit),
+				server.WithDiscussion(serveDiscussion),
+			)
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			httpServer := &http.Server{
+				Addr:              serveListen,
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			go func() {
+				ticker := time.NewTicker(servePurgeInterval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case now := <-ticker.C:
+						_, _ = storage.PurgeExpired(ctx, now)
+					}
+				}
+			}()
+
+			go func() {
+				<-ctx.Done()
+
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+
+				_ = httpServer.Shutdown(shutdownCtx)
+			}()
+
+			_, _ = fmt.Fprintf(os.Stderr, "listening on %s\n", serveListen)
+
+			if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("cannot serve: %w", err)
+			}
+
+			return nil
+		},
+	}
+
 	initCmd = &cobra.Command{
 		Use:          "init",
 		Short:        "Generate a configuration file",
@@ -405,6 +1014,325 @@ var (
 	}
 )
 
+// saveAttachmentsTo writes the attachments in dir, creating it if needed.
+// Names come from the paste author so they are sanitized, and existing
+// files are only replaced when force is set.
+func saveAttachmentsTo(dir string, attachments []privatebin.Attachment, force bool) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("cannot create %q directory: %w", dir, err)
+	}
+
+	used := make(map[string]bool, len(attachments))
+
+	for i, a := range attachments {
+		name := a.SafeName()
+		if name == "" {
+			name = fmt.Sprintf("attachment-%d", i+1)
+		}
+
+		// Several attachments may share a name, keep all of them.
+		ext := filepath.Ext(name)
+		stem := strings.TrimSuffix(name, ext)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+		}
+		used[name] = true
+
+		path := filepath.Join(dir, name)
+
+		if force {
+			// Remove instead of truncating so an existing symbolic link
+			// is replaced rather than followed.
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("cannot remove %q file: %w", path, err)
+			}
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			if errors.Is(err, os.ErrExist) {
+				return fmt.Errorf("%q already exists, use --force to overwrite it", path)
+			}
+			return fmt.Errorf("cannot create %q file: %w", path, err)
+		}
+
+		_, err = f.Write(a.Data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("cannot write %q file: %w", path, err)
+		}
+
+		_, _ = fmt.Fprintf(os.Stderr, "saved %s (%s, %d bytes)\n", path, a.MimeType, len(a.Data))
+	}
+
+	return nil
+}
+
+// pasteJSON keeps the attachment and attachment_name fields of the first
+// attachment for compatibility, attachments lists all of them.
+func pasteJSON(paste privatebin.Paste) map[string]any {
+	var (
+		attachmentName string
+		attachment     []byte
+		attachments    = make([]map[string]string, 0, len(paste.Attachments))
+	)
+
+	if len(paste.Attachments) > 0 {
+		attachmentName = paste.Attachments[0].Name
+		attachment = paste.Attachments[0].Data
+	}
+
+	for _, a := range paste.Attachments {
+		attachments = append(
+			attachments,
+			map[string]string{
+				"name":      a.Name,
+				"mime_type": a.MimeType,
+				"data":      base64.StdEncoding.EncodeToString(a.Data),
+			},
+		)
+	}
+
+	return map[string]any{
+		"attachment_name": attachmentName,
+		"attachment":      base64.StdEncoding.EncodeToString(attachment),
+		"attachments":     attachments,
+		"data":            base64.StdEncoding.EncodeToString(paste.Data),
+	}
+}
+
+func printCommentTree(w io.Writer, nodes []*privatebin.CommentNode, depth int) {
+	indent := strings.Repeat("    ", depth)
+
+	for _, node := range nodes {
+		nickname := node.Comment.Nickname
+		if nickname == "" {
+			nickname = "Anonymous"
+		}
+
+		header := nickname
+		if !node.Comment.Created.IsZero() {
+			header += " (" + node.Comment.Created.Format(time.RFC3339) + ")"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s* %s\n", indent, header)
+		if node.Comment.Err != nil {
+			_, _ = fmt.Fprintf(w, "%s  [%v]\n", indent, node.Comment.Err)
+		} else {
+			for _, line := range strings.Split(node.Comment.Text, "\n") {
+				_, _ = fmt.Fprintf(w, "%s  %s\n", indent, line)
+			}
+		}
+
+		printCommentTree(w, node.Replies, depth+1)
+	}
+}
+
+func commentTreeJSON(nodes []*privatebin.CommentNode) []map[string]any {
+	comments := []map[string]any{}
+
+	for _, node := range nodes {
+		var created *time.Time
+		if !node.Comment.Created.IsZero() {
+			created = &node.Comment.Created
+		}
+
+		comment := map[string]any{
+			"comment_id": node.Comment.CommentID,
+			"paste_id":   node.Comment.PasteID,
+			"parent_id":  node.Comment.ParentID,
+			"nickname":   node.Comment.Nickname,
+			"text":       node.Comment.Text,
+			"created":    created,
+			"icon":       node.Comment.Icon,
+			"replies":    commentTreeJSON(node.Replies),
+		}
+
+		if node.Comment.Err != nil {
+			comment["error"] = node.Comment.Err.Error()
+		}
+
+		comments = append(comments, comment)
+	}
+
+	return comments
+}
+
+// connect builds the client of the selected bin. Only the commands
+// contacting the instance call it, so the others never resolve the
+// secrets of the bin nor run their commands.
+func connect(cmd *cobra.Command) error {
+	c, err := newClient(cmd, binCfg)
+	if err != nil {
+		return err
+	}
+
+	client = c
+
+	return nil
+}
+
+// newClient builds the client of the bin, with the transport settings of
+// the command line flags.
+func newClient(cmd *cobra.Command, bin *BinCfg) (*privatebin.Client, error) {
+	options := append([]privatebin.Option{}, clientOptions...)
+
+	// Secrets are only resolved for this bin, the commands and files
+	// of the other bins are left alone.
+	authPassword, err := bin.Auth.Password.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve auth password: %w", err)
+	}
+
+	options = append(
+		options,
+		privatebin.WithBasicAuth(
+			bin.Auth.Username,
+			authPassword,
+		),
+	)
+
+	// Extra header fields often carry tokens, keep them out of
+	// the logs.
+	for k, secret := range bin.ExtraHeaderFields {
+		v, err := secret.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve %q header field: %w", k, err)
+		}
+
+		options = append(
+			options,
+			privatebin.WithSensitiveHeaderField(k, v),
+		)
+	}
+
+	for _, value := range extraHeaderFields {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid header field format: '%s', expected 'key: value'", value)
+		}
+
+		options = append(
+			options,
+			privatebin.WithSensitiveHeaderField(
+				strings.TrimSpace(parts[0]),
+				strings.TrimSpace(parts[1]),
+			),
+		)
+	}
+
+	bin = binWithFlags(cmd, bin)
+
+	tlsConfig, err := newTLSConfig(bin)
+	if err != nil {
+		return nil, err
+	}
+
+	if tlsConfig != nil {
+		options = append(
+			options,
+			privatebin.WithTLSConfig(tlsConfig),
+		)
+	}
+
+	proxyAddr := bin.Proxy
+	if proxy != "" {
+		proxyAddr = proxy
+	}
+
+	if proxyAddr != "" {
+		proxyURL, err := url.Parse(proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse proxy url %q: %w", proxyAddr, err)
+		}
+
+		options = append(
+			options,
+			privatebin.WithProxyURL(*proxyURL),
+		)
+	}
+
+	if *bin.Retries < 0 {
+		return nil, fmt.Errorf("invalid retries: %d, must be positive", *bin.Retries)
+	}
+
+	if *bin.Retries > 0 {
+		retryPolicy := privatebin.DefaultRetryPolicy()
+		retryPolicy.MaxAttempts = *bin.Retries + 1
+
+		options = append(
+			options,
+			privatebin.WithRetryPolicy(retryPolicy),
+		)
+	}
+
+	host, err := url.Parse(bin.Host)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q bin %q host: %w", bin.Name, bin.Host, err)
+	}
+
+	return privatebin.NewClient(*host, options...), nil
+}
+
+// binWithFlags returns a copy of the bin with the transport settings
+// overridden by the command line flags that were set, bin is left alone.
+func binWithFlags(cmd *cobra.Command, bin *BinCfg) *BinCfg {
+	b := *bin
+
+	if skipTLSVerify {
+		b.SkipTLSVerify = &skipTLSVerify
+	}
+
+	if cmd.Flags().Changed("ca-file") {
+		b.CAFile = caFile
+	}
+
+	if cmd.Flags().Changed("client-cert") {
+		b.ClientCert = clientCert
+	}
+
+	if cmd.Flags().Changed("client-key") {
+		b.ClientKey = clientKey
+	}
+
+	if cmd.Flags().Changed("min-tls-version") {
+		b.MinTLSVersion = minTLSVersion
+	}
+
+	if cmd.Flags().Changed("pin") {
+		b.Pins = pins
+	}
+
+	if cmd.Flags().Changed("retries") {
+		b.Retries = &retries
+	}
+
+	return &b
+}
+
+// checkTrustedHost refuses instances the selected bin does not serve
+// unless --insecure is given. The bin credentials and header fields are
+// then dropped: they belong to the bin host, not to the unknown instance.
+func checkTrustedHost(link url.URL) error {
+	if _, ok := matchBinHost(binCfg.Host, link); ok {
+		return nil
+	}
+
+	if !insecure {
+		return fmt.Errorf("untrusted privatebin instance use --insecure flag or add it to the configuration")
+	}
+
+	untrusted := *binCfg
+	untrusted.Host = link.String()
+	untrusted.Auth = AuthCfg{}
+	untrusted.ExtraHeaderFields = nil
+	binCfg = &untrusted
+
+	return nil
+}
+
 func configFileCandidates() ([]string, error) {
 	var candidates []string
 	seen := make(map[string]bool)
@@ -471,30 +1399,117 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&binName, "bin", "b", "", "the name of the privatebin instance to use (default \"\")")
 	rootCmd.PersistentFlags().StringSliceVarP(&extraHeaderFields, "header", "H", []string{}, "extra HTTP header fields to include in the request sent")
 	rootCmd.PersistentFlags().StringVar(&proxy, "proxy", "", "proxy URL to use for requests (e.g. socks5://127.0.0.1:9050 for TOR)")
-
-	createCmd.Flags().StringVar(&expire, "expire", "", "the time to live of the paste")
+	rootCmd.PersistentFlags().StringVar(&caFile, "ca-file", "", "PEM file of the certificate authorities trusted in addition to the system ones")
+	rootCmd.PersistentFlags().StringVar(&clientCert, "client-cert", "", "PEM file of the client certificate for mutual TLS")
+	rootCmd.PersistentFlags().StringVar(&clientKey, "client-key", "", "PEM file of the client certificate private key")
+	rootCmd.PersistentFlags().StringVar(&minTLSVersion, "min-tls-version", "", "the minimum TLS version, can be 1.0, 1.1, 1.2 or 1.3")
+	rootCmd.PersistentFlags().StringArrayVar(&pins, "pin", nil, "a pinned SPKI SHA-256 hash (sha256/<base64>) of the server certificate chain, repeat to pin several keys")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "log the requests sent and the responses received on stderr")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "like --verbose, with the request headers and the duration of each encryption step")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", 0, "the number of times a failed request is retried")
+
+	createCmd.Flags().StringVar(&expire, "expire", "", "the time to live of the paste, can be 5min, 10min, 1hour, 1day, 1week, 1month, 1year, never or a duration rounded to the nearest option")
+	createCmd.Flags().StringVar(&expireAt, "expire-at", "", "the RFC 3339 date the paste should expire, rounded to the nearest expire option")
+	createCmd.MarkFlagsMutuallyExclusive("expire", "expire-at")
 	createCmd.Flags().BoolVar(&openDiscussion, "open-discussion", false, "enable discussion on the paste")
 	createCmd.Flags().BoolVar(&burnAfterReading, "burn-after-reading", false, "delete the paste after reading")
 	createCmd.Flags().BoolVar(&gzip, "gzip", true, "gzip the paste data")
 	createCmd.Flags().StringVar(&formatter, "formatter", "", "the text formatter to use, can be plaintext, markdown or syntaxhighlighting")
-	createCmd.Flags().StringVar(&password, "password", "", "the paste password")
-	createCmd.Flags().StringVar(&filename, "filename", "", "read filepath instead of stdin")
+	addPasswordFlags(createCmd)
+	createCmd.Flags().IntVar(&generatePassword, "generate-password", 0, "protect the paste with a random password of the given length, printed on stderr")
+	createCmd.Flags().Lookup("generate-password").NoOptDefVal = strconv.Itoa(privatebin.DefaultPasswordLength)
+	createCmd.MarkFlagsMutuallyExclusive("generate-password", "password", "password-prompt", "password-file", "password-fd")
+	createCmd.Flags().StringArrayVar(&filenames, "filename", nil, "read filepath instead of stdin, repeat to attach several files")
 	createCmd.Flags().BoolVar(&attachment, "attachment", false, "create the paste as an attachment")
+	createCmd.Flags().Int64Var(&maxSize, "max-size", 0, "the maximum size in bytes of the paste input, 0 for no limit")
 	createCmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "skip TLS certificate verification")
 
 	showCmd.Flags().BoolVar(&insecure, "insecure", false, "allow reading paste from untrusted instance")
 	showCmd.Flags().BoolVar(&confirmBurn, "confirm-burn", false, "confirm paste opening, it will be deleted immediately afterwards")
-	showCmd.Flags().StringVar(&password, "password", "", "the paste password")
+	addPasswordFlags(showCmd)
+	showCmd.Flags().BoolVar(&skipComments, "skip-comments", false, "do not decrypt the paste comments")
+	showCmd.Flags().StringVar(&saveAttachments, "save-attachments", "", "write the paste attachments in the given directory")
+	showCmd.Flags().BoolVar(&attachmentStdout, "attachment-stdout", false, "write the paste attachment to stdout instead of the paste text")
+	showCmd.Flags().BoolVar(&force, "force", false, "overwrite existing files when saving attachments")
+	showCmd.MarkFlagsMutuallyExclusive("save-attachments", "attachment-stdout")
 	showCmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "skip TLS certificate verification")
 
+	inspectCmd.Flags().BoolVar(&insecure, "insecure", false, "allow inspecting paste from untrusted instance")
+	inspectCmd.Flags().BoolVar(&confirmBurn, "confirm-burn", false, "confirm fetching a burn after reading paste, it will be deleted immediately afterwards")
+	inspectCmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "skip TLS certificate verification")
+
+	commentCmd.Flags().StringVar(&replyTo, "reply-to", "", "the id of the comment to reply to (default to the paste)")
+	commentCmd.Flags().StringVar(&nickname, "nickname", "", "the nickname displayed with the comment")
+	addPasswordFlags(commentCmd)
+	commentCmd.Flags().BoolVar(&gzip, "gzip", true, "gzip the comment data")
+	commentCmd.Flags().BoolVar(&insecure, "insecure", false, "allow commenting paste from untrusted instance")
+	commentCmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "skip TLS certificate verification")
+
+	createCmd.Flags().BoolVar(&history, "history", false, "record the paste and its delete token in the local history")
+
+	deleteCmd.Flags().StringVar(&deleteToken, "token", "", "the paste delete token")
+	deleteCmd.Flags().BoolVar(&insecure, "insecure", false, "allow deleting paste from untrusted instance")
+	deleteCmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "skip TLS certificate verification")
+
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "the address to listen on")
+	serveCmd.Flags().StringVar(&serveStorage, "storage", "filesystem", "the storage backend, can be filesystem or memory")
+	serveCmd.Flags().StringVar(&serveDataDir, "data-dir", "data", "the directory of the filesystem storage")
+	serveCmd.Flags().StringVar(&serveDefaultExpire, "default-expire", "1week", "the expire option used when the client sends an unknown one")
+	serveCmd.Flags().IntVar(&serveSizeLimit, "size-limit", 10*1024*1024, "the maximum size in bytes of the encrypted data")
+	serveCmd.Flags().BoolVar(&serveDiscussion, "discussion", true, "allow open discussion on pastes")
+	serveCmd.Flags().DurationVar(&servePurgeInterval, "purge-interval", 10*time.Minute, "the interval between two purges of expired pastes")
+
 	initCmd.Flags().BoolVar(&force, "force", false, "overwrite existing configuration file")
 	initCmd.Flags().StringVar(&initHost, "host", "https://privatebin.net", "the host of the default privatebin instance")
 
-	rootCmd.AddCommand(showCmd, createCmd, initCmd)
+	historyListCmd.Flags().BoolVar(&historyAll, "all", false, "also list the expired pastes")
+	historyCmd.AddCommand(historyListCmd, historyShowCmd, historyForgetCmd, historyPruneCmd, historyDeleteCmd)
+
+	rootCmd.AddCommand(showCmd, inspectCmd, createCmd, commentCmd, deleteCmd, historyCmd, serveCmd, initCmd)
+}
+
+// pasteURLAnnotation marks the commands taking a paste URL as first
+// argument, the bin serving it is selected when --bin is not given.
+const pasteURLAnnotation = "paste-url"
+
+// Exit codes returned by the CLI, so scripts can react to the failure
+// without parsing the error message.
+const (
+	exitFailure            = 1
+	exitPasteNotFound      = 3
+	exitDecryptionFailed   = 4
+	exitBurnNotConfirmed   = 5
+	exitRateLimited        = 6
+	exitInvalidDeleteToken = 7
+	exitServerError        = 8
+	exitPolicyViolation    = 9
+)
+
+func exitCode(err error) int {
+	var serverErr *privatebin.ServerError
+
+	switch {
+	case errors.Is(err, privatebin.ErrPasteNotFound):
+		return exitPasteNotFound
+	case errors.Is(err, privatebin.ErrDecryptionFailed):
+		return exitDecryptionFailed
+	case errors.Is(err, privatebin.ErrBurnNotConfirmed):
+		return exitBurnNotConfirmed
+	case errors.Is(err, privatebin.ErrRateLimited):
+		return exitRateLimited
+	case errors.Is(err, privatebin.ErrInvalidDeleteToken):
+		return exitInvalidDeleteToken
+	case errors.Is(err, privatebin.ErrPolicyViolation):
+		return exitPolicyViolation
+	case errors.As(err, &serverErr):
+		return exitServerError
+	default:
+		return exitFailure
+	}
 }
 
 func main() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
@@ -0,0 +1,254 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.gearno.de/privatebin/v2"
+)
+
+func TestCheckTrustedHost(t *testing.T) {
+	bin := &BinCfg{
+		Name:              "work",
+		Host:              "https://bin.example.com",
+		Auth:              AuthCfg{Username: "user", Password: Secret{Value: "secret"}},
+		ExtraHeaderFields: map[string]Secret{"X-Token": {Value: "secret"}},
+	}
+
+	t.Cleanup(func() { binCfg, insecure = nil, false })
+
+	tests := []struct {
+		name        string
+		link        string
+		insecure    bool
+		wantErr     bool
+		wantDropped bool
+	}{
+		{name: "bin host", link: "https://bin.example.com/"},
+		{name: "bin host with insecure", link: "https://BIN.example.com:443/", insecure: true},
+		{name: "unknown host", link: "https://other.example.com/", wantErr: true},
+		{name: "unknown host with insecure", link: "https://other.example.com/", insecure: true, wantDropped: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binCfg, insecure = bin, tt.insecure
+
+			link, err := url.Parse(tt.link)
+			require.NoError(t, err)
+
+			err = checkTrustedHost(*link)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			if !tt.wantDropped {
+				assert.Same(t, bin, binCfg)
+				return
+			}
+
+			assert.Equal(t, tt.link, binCfg.Host)
+			assert.Equal(t, AuthCfg{}, binCfg.Auth)
+			assert.Empty(t, binCfg.ExtraHeaderFields)
+			assert.Equal(t, "user", bin.Auth.Username, "the bin configuration is left alone")
+		})
+	}
+}
+
+func TestSaveAttachmentsTo(t *testing.T) {
+	save := func(t *testing.T, dir string, attachments []privatebin.Attachment, force bool) error {
+		t.Helper()
+
+		var err error
+		captureStderr(t, func() { err = saveAttachmentsTo(dir, attachments, force) })
+
+		return err
+	}
+
+	t.Run("Sanitized and deduplicated names", func(t *testing.T) {
+		dir := t.TempDir()
+
+		attachments := []privatebin.Attachment{
+			{Name: "../report.txt", Data: []byte("first")},
+			{Name: "/tmp/report.txt", Data: []byte("second")},
+			{Name: "report.txt", Data: []byte("third")},
+			{Name: "..", Data: []byte("unnamed")},
+		}
+
+		require.NoError(t, save(t, dir, attachments, false))
+
+		for name, want := range map[string]string{
+			"report.txt":   "first",
+			"report-2.txt": "second",
+			"report-3.txt": "third",
+			"attachment-4": "unnamed",
+		} {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			require.NoError(t, err, name)
+			assert.Equal(t, want, string(data), name)
+		}
+
+		entries, err := os.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, entries, 4)
+	})
+
+	t.Run("Existing file without force", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "notes.txt")
+		require.NoError(t, os.WriteFile(path, []byte("original"), 0o600))
+
+		err := save(t, dir, []privatebin.Attachment{{Name: "notes.txt", Data: []byte("new")}}, false)
+		require.ErrorContains(t, err, "use --force to overwrite it")
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "original", string(data))
+	})
+
+	t.Run("Existing file with force", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "notes.txt")
+		require.NoError(t, os.WriteFile(path, []byte("original"), 0o600))
+
+		require.NoError(t, save(t, dir, []privatebin.Attachment{{Name: "notes.txt", Data: []byte("new")}}, true))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "new", string(data))
+	})
+
+	t.Run("Existing symbolic link", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links need privileges on windows")
+		}
+
+		for _, force := range []bool{false, true} {
+			dir := t.TempDir()
+			target := filepath.Join(t.TempDir(), "target")
+			require.NoError(t, os.WriteFile(target, []byte("target"), 0o600))
+
+			link := filepath.Join(dir, "notes.txt")
+			require.NoError(t, os.Symlink(target, link))
+
+			err := save(t, dir, []privatebin.Attachment{{Name: "notes.txt", Data: []byte("new")}}, force)
+			if force {
+				require.NoError(t, err)
+
+				fi, err := os.Lstat(link)
+				require.NoError(t, err)
+				assert.True(t, fi.Mode().IsRegular(), "the link is replaced by a file")
+			} else {
+				require.ErrorContains(t, err, "use --force to overwrite it")
+			}
+
+			data, err := os.ReadFile(target)
+			require.NoError(t, err)
+			assert.Equal(t, "target", string(data), "the link target is never written")
+		}
+	})
+
+	t.Run("Dangling symbolic link", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("symbolic links need privileges on windows")
+		}
+
+		dir := t.TempDir()
+		target := filepath.Join(t.TempDir(), "missing")
+		require.NoError(t, os.Symlink(target, filepath.Join(dir, "notes.txt")))
+
+		err := save(t, dir, []privatebin.Attachment{{Name: "notes.txt", Data: []byte("new")}}, false)
+		require.ErrorContains(t, err, "use --force to overwrite it")
+
+		_, err = os.Stat(target)
+		assert.ErrorIs(t, err, os.ErrNotExist, "the link target is not created")
+	})
+}
+
+func TestBinWithFlags(t *testing.T) {
+	newCmd := func(t *testing.T, args ...string) *cobra.Command {
+		t.Cleanup(func() {
+			skipTLSVerify, caFile, clientCert, clientKey = false, "", "", ""
+			minTLSVersion, pins, retries = "", nil, 0
+		})
+
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolVar(&skipTLSVerify, "skip-tls-verify", false, "")
+		cmd.Flags().StringVar(&caFile, "ca-file", "", "")
+		cmd.Flags().StringVar(&clientCert, "client-cert", "", "")
+		cmd.Flags().StringVar(&clientKey, "client-key", "", "")
+		cmd.Flags().StringVar(&minTLSVersion, "min-tls-version", "", "")
+		cmd.Flags().StringArrayVar(&pins, "pin", nil, "")
+		cmd.Flags().IntVar(&retries, "retries", 0, "")
+		require.NoError(t, cmd.ParseFlags(args))
+
+		return cmd
+	}
+
+	newBin := func() *BinCfg {
+		return &BinCfg{
+			CAFile:     "/cfg/ca.pem",
+			ClientCert: "/cfg/cert.pem",
+			ClientKey:  "/cfg/key.pem",
+			Retries:    new(3),
+		}
+	}
+
+	t.Run("no flag", func(t *testing.T) {
+		bin := newBin()
+		got := binWithFlags(newCmd(t), bin)
+
+		assert.Equal(t, newBin(), got)
+		assert.NotSame(t, bin, got)
+	})
+
+	t.Run("client cert only", func(t *testing.T) {
+		bin := newBin()
+		got := binWithFlags(newCmd(t, "--client-cert", "/flag/cert.pem"), bin)
+
+		assert.Equal(t, "/flag/cert.pem", got.ClientCert)
+		assert.Equal(t, "/cfg/key.pem", got.ClientKey)
+		assert.Equal(t, newBin(), bin)
+	})
+
+	t.Run("client key only", func(t *testing.T) {
+		bin := newBin()
+		got := binWithFlags(newCmd(t, "--client-key", "/flag/key.pem"), bin)
+
+		assert.Equal(t, "/cfg/cert.pem", got.ClientCert)
+		assert.Equal(t, "/flag/key.pem", got.ClientKey)
+		assert.Equal(t, newBin(), bin)
+	})
+
+	t.Run("retries set to zero", func(t *testing.T) {
+		bin := newBin()
+		got := binWithFlags(newCmd(t, "--retries", "0"), bin)
+
+		require.NotNil(t, got.Retries)
+		assert.Equal(t, 0, *got.Retries)
+		assert.Equal(t, 3, *bin.Retries)
+	})
+}
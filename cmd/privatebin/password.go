@@ -0,0 +1,215 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"go.gearno.de/privatebin/v2"
+)
+
+const (
+	passwordEnv = "PRIVATEBIN_PASSWORD"
+
+	// maxPasswordAttempts is the number of passwords asked by show
+	// before giving up.
+	maxPasswordAttempts = 3
+)
+
+var (
+	errNoTerminal = errors.New("no terminal attached")
+)
+
+type (
+	// terminal is where passwords are asked. Stdin cannot be used when
+	// it carries the paste content, the controlling terminal is
+	// preferred.
+	terminal struct {
+		in    *os.File
+		out   io.Writer
+		close func() error
+	}
+)
+
+// addPasswordFlags registers the flags setting the paste password on cmd.
+func addPasswordFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&password, "password", "", "the paste password, visible in the shell history and the process list, prefer the other password flags")
+	cmd.Flags().BoolVar(&passwordPrompt, "password-prompt", false, "read the paste password from the terminal")
+	cmd.Flags().StringVar(&passwordFile, "password-file", "", "read the paste password from the first line of a file")
+	cmd.Flags().IntVar(&passwordFD, "password-fd", 0, "read the paste password from a file descriptor")
+	cmd.MarkFlagsMutuallyExclusive("password", "password-prompt", "password-file", "password-fd")
+}
+
+// readPassword returns the paste password set with the password flags or
+// the PRIVATEBIN_PASSWORD environment variable, nil when there is none.
+// The password typed at the prompt is asked twice when confirm is set.
+func readPassword(cmd *cobra.Command, confirm bool) ([]byte, error) {
+	switch {
+	case cmd.Flags().Changed("password"):
+		return []byte(password), nil
+	case passwordPrompt:
+		p, err := promptPassword("Paste password: ")
+		if err != nil {
+			return nil, err
+		}
+
+		if len(p) == 0 {
+			return nil, fmt.Errorf("cannot use an empty password")
+		}
+
+		if confirm {
+			again, err := promptPassword("Confirm password: ")
+			if err != nil {
+				return nil, err
+			}
+
+			if !bytes.Equal(p, again) {
+				return nil, fmt.Errorf("passwords do not match")
+			}
+		}
+
+		return p, nil
+	case passwordFile != "":
+		warnReadableByOthers(passwordFile, "password file")
+
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read password file: %w", err)
+		}
+
+		return firstLine(data), nil
+	case cmd.Flags().Changed("password-fd"):
+		f := os.NewFile(uintptr(passwordFD), "password-fd")
+		if f == nil {
+			return nil, fmt.Errorf("invalid password file descriptor: %d", passwordFD)
+		}
+		defer func() { _ = f.Close() }()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read password from file descriptor %d: %w", passwordFD, err)
+		}
+
+		return firstLine(data), nil
+	}
+
+	if v, ok := os.LookupEnv(passwordEnv); ok {
+		return []byte(v), nil
+	}
+
+	return nil, nil
+}
+
+// passwordRetry returns the function asking another password when a
+// paste cannot be decrypted, nil when no terminal is attached. tried
+// reports whether a password was already given.
+func passwordRetry(tried bool) func(context.Context) ([]byte, error) {
+	t, err := openTerminal()
+	if err != nil {
+		return nil
+	}
+	_ = t.close()
+
+	attempts := 0
+	return func(ctx context.Context) ([]byte, error) {
+		if attempts >= maxPasswordAttempts {
+			return nil, privatebin.ErrDecryptionFailed
+		}
+		attempts++
+
+		prompt := "Paste password: "
+		if tried {
+			prompt = "Wrong password, try again: "
+		}
+		tried = true
+
+		return promptPassword(prompt)
+	}
+}
+
+func promptPassword(prompt string) ([]byte, error) {
+	t, err := openTerminal()
+	if err != nil {
+		return nil, fmt.Errorf("cannot prompt password: %w", err)
+	}
+	defer func() { _ = t.close() }()
+
+	_, _ = fmt.Fprint(t.out, prompt)
+	p, err := term.ReadPassword(int(t.in.Fd()))
+	_, _ = fmt.Fprintln(t.out)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read password: %w", err)
+	}
+
+	return p, nil
+}
+
+// promptConfirm asks a yes or no question on the terminal, anything but
+// "y" or "yes" is a no. It fails when no terminal is attached.
+func promptConfirm(question string) (bool, error) {
+	t, err := openTerminal()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = t.close() }()
+
+	_, _ = fmt.Fprintf(t.out, "%s [y/N] ", question)
+	answer, err := bufio.NewReader(t.in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, fmt.Errorf("cannot read answer: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// openTerminal opens the controlling terminal, falling back to stdin and
+// stderr when they are both terminals.
+func openTerminal() (*terminal, error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
+		if term.IsTerminal(int(tty.Fd())) {
+			return &terminal{in: tty, out: tty, close: tty.Close}, nil
+		}
+		_ = tty.Close()
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd())) {
+		return &terminal{in: os.Stdin, out: os.Stderr, close: func() error { return nil }}, nil
+	}
+
+	return nil, errNoTerminal
+}
+
+func firstLine(data []byte) []byte {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+
+	return bytes.TrimSuffix(data, []byte("\r"))
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPasswordCmd returns a command with the password flags set to flags,
+// the mutual exclusion of the flags is not checked.
+func newPasswordCmd(t *testing.T, flags map[string]string) *cobra.Command {
+	t.Helper()
+
+	cmd := &cobra.Command{}
+	addPasswordFlags(cmd)
+
+	for k, v := range flags {
+		require.NoError(t, cmd.Flags().Set(k, v))
+	}
+
+	return cmd
+}
+
+func writePasswordFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestReadPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   map[string]string
+		env     *string
+		want    []byte
+		wantErr string
+	}{
+		{
+			name: "nothing set",
+		},
+		{
+			name:  "flag",
+			flags: map[string]string{"password": "from-flag\n"},
+			want:  []byte("from-flag\n"),
+		},
+		{
+			name:  "empty flag",
+			flags: map[string]string{"password": ""},
+			want:  []byte(""),
+		},
+		{
+			name:  "file",
+			flags: map[string]string{"password-file": writePasswordFile(t, "from-file\n")},
+			want:  []byte("from-file"),
+		},
+		{
+			name:  "file with crlf",
+			flags: map[string]string{"password-file": writePasswordFile(t, "from-file\r\n")},
+			want:  []byte("from-file"),
+		},
+		{
+			name:  "file first line only",
+			flags: map[string]string{"password-file": writePasswordFile(t, "line1\nline2\n")},
+			want:  []byte("line1"),
+		},
+		{
+			name:  "file without newline",
+			flags: map[string]string{"password-file": writePasswordFile(t, "from-file")},
+			want:  []byte("from-file"),
+		},
+		{
+			name:    "missing file",
+			flags:   map[string]string{"password-file": filepath.Join(t.TempDir(), "missing")},
+			wantErr: "cannot read password file",
+		},
+		{
+			name: "env",
+			env:  new("from-env\n"),
+			want: []byte("from-env\n"),
+		},
+		{
+			name: "empty env",
+			env:  new(""),
+			want: []byte(""),
+		},
+		{
+			name:  "flag before env",
+			flags: map[string]string{"password": "from-flag"},
+			env:   new("from-env"),
+			want:  []byte("from-flag"),
+		},
+		{
+			name:  "file before env",
+			flags: map[string]string{"password-file": writePasswordFile(t, "from-file\n")},
+			env:   new("from-env"),
+			want:  []byte("from-file"),
+		},
+		{
+			name: "flag before file",
+			flags: map[string]string{
+				"password":      "from-flag",
+				"password-file": writePasswordFile(t, "from-file\n"),
+			},
+			want: []byte("from-flag"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != nil {
+				t.Setenv(passwordEnv, *tt.env)
+			} else {
+				// t.Setenv restores the variable once the test ends.
+				t.Setenv(passwordEnv, "")
+				require.NoError(t, os.Unsetenv(passwordEnv))
+			}
+
+			got, err := readPassword(newPasswordCmd(t, tt.flags), false)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadPassword_FileDescriptor(t *testing.T) {
+	t.Setenv(passwordEnv, "from-env")
+
+	for _, tt := range []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "trailing newline", content: "from-fd\n", want: "from-fd"},
+		{name: "first line only", content: "line1\r\nline2\n", want: "line1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(writePasswordFile(t, tt.content))
+			require.NoError(t, err)
+
+			flags := map[string]string{"password-fd": strconv.Itoa(int(f.Fd()))}
+			got, err := readPassword(newPasswordCmd(t, flags), false)
+
+			// readPassword closes the descriptor, f.Close only marks
+			// the file closed so the descriptor is not closed twice.
+			_ = f.Close()
+
+			require.NoError(t, err)
+			assert.Equal(t, []byte(tt.want), got)
+		})
+	}
+}
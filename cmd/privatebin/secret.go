@@ -0,0 +1,207 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+type (
+	// Secret is a configuration value written inline or read, when the
+	// bin using it is selected, from an environment variable, a file or
+	// the output of a command:
+	//
+	//	"s$cr$t"
+	//	{"env": "PRIVATEBIN_PASSWORD"}
+	//	{"file": "~/.config/privatebin/password"}
+	//	{"command": ["pass", "show", "privatebin"]}
+	Secret struct {
+		Value   string
+		Env     string
+		File    string
+		Command []string
+	}
+)
+
+func (s *Secret) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*s = Secret{}
+		return nil
+	case bytes.HasPrefix(data, []byte("\"")):
+		*s = Secret{}
+		return json.Unmarshal(data, &s.Value)
+	}
+
+	var source struct {
+		Env     string   `json:"env"`
+		File    string   `json:"file"`
+		Command []string `json:"command"`
+	}
+
+	if err := json.Unmarshal(data, &source); err != nil {
+		return fmt.Errorf("secret must be a string or an object with one of 'env', 'file', 'command': %w", err)
+	}
+
+	n := 0
+	for _, set := range []bool{source.Env != "", source.File != "", len(source.Command) > 0} {
+		if set {
+			n++
+		}
+	}
+
+	if n != 1 {
+		return fmt.Errorf("secret object must have exactly one of 'env', 'file', 'command'")
+	}
+
+	*s = Secret{Env: source.Env, File: source.File, Command: source.Command}
+
+	return nil
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	switch {
+	case s.Env != "":
+		return json.Marshal(map[string]string{"env": s.Env})
+	case s.File != "":
+		return json.Marshal(map[string]string{"file": s.File})
+	case len(s.Command) > 0:
+		return json.Marshal(map[string][]string{"command": s.Command})
+	default:
+		return json.Marshal(s.Value)
+	}
+}
+
+// IsInline reports whether the secret value is written in the
+// configuration file.
+func (s Secret) IsInline() bool {
+	return s.Env == "" && s.File == "" && len(s.Command) == 0 && s.Value != ""
+}
+
+// Resolve returns the secret value. The trailing newline of files and
+// command outputs is removed.
+func (s Secret) Resolve() (string, error) {
+	switch {
+	case s.Env != "":
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+
+		return v, nil
+	case s.File != "":
+		path, err := expandHome(s.File)
+		if err != nil {
+			return "", err
+		}
+
+		warnReadableByOthers(path, "secret file")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("cannot read secret file: %w", err)
+		}
+
+		return trimNewline(string(data)), nil
+	case len(s.Command) > 0:
+		cmd := exec.Command(s.Command[0], s.Command[1:]...)
+		// Stdin may carry the paste content, keep it for privatebin.
+		cmd.Stdin = nil
+		cmd.Stderr = os.Stderr
+
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("cannot run secret command %q: %w", s.Command[0], err)
+		}
+
+		return trimNewline(string(out)), nil
+	default:
+		return s.Value, nil
+	}
+}
+
+// hasInlineSecrets reports whether the configuration holds secret values
+// written in the file itself.
+func (c *Cfg) hasInlineSecrets() bool {
+	for _, v := range c.ExtraHeaderFields {
+		if v.IsInline() {
+			return true
+		}
+	}
+
+	for _, bin := range c.Bin {
+		if bin.Auth.Password.IsInline() {
+			return true
+		}
+
+		for _, v := range bin.ExtraHeaderFields {
+			if v.IsInline() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// warnReadableByOthers prints a warning on stderr when the file at path
+// can be read by the group or other users.
+func warnReadableByOthers(path, what string) {
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	if fi.Mode().Perm()&0o044 != 0 {
+		_, _ = fmt.Fprintf(
+			os.Stderr,
+			"warning: %s %s is readable by other users (mode %04o), consider running: chmod 600 %s\n",
+			what,
+			path,
+			fi.Mode().Perm(),
+			path,
+		)
+	}
+}
+
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot expand %q: %w", path, err)
+	}
+
+	return home + path[1:], nil
+}
+
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStderr returns what fn writes on the standard error.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	fn()
+
+	require.NoError(t, w.Close())
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(out)
+}
+
+func TestSecret_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Secret
+		wantErr bool
+	}{
+		{name: "inline", input: `"s$cr$t"`, want: Secret{Value: "s$cr$t"}},
+		{name: "null", input: `null`, want: Secret{}},
+		{name: "env", input: `{"env": "TOKEN"}`, want: Secret{Env: "TOKEN"}},
+		{name: "file", input: `{"file": "~/token"}`, want: Secret{File: "~/token"}},
+		{name: "command", input: `{"command": ["pass", "show", "bin"]}`, want: Secret{Command: []string{"pass", "show", "bin"}}},
+		{name: "no source", input: `{}`, wantErr: true},
+		{name: "several sources", input: `{"env": "TOKEN", "file": "token"}`, wantErr: true},
+		{name: "unknown type", input: `42`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s Secret
+			err := json.Unmarshal([]byte(tt.input), &s)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, s)
+
+			data, err := json.Marshal(s)
+			require.NoError(t, err)
+
+			var roundTrip Secret
+			require.NoError(t, json.Unmarshal(data, &roundTrip))
+			assert.Equal(t, tt.want, roundTrip)
+		})
+	}
+}
+
+func TestSecret_Resolve(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("secret commands use sh")
+	}
+
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "token")
+	require.NoError(t, os.WriteFile(file, []byte("from-file\n"), 0o600))
+
+	crlfFile := filepath.Join(dir, "crlf")
+	require.NoError(t, os.WriteFile(crlfFile, []byte("from-file\r\n"), 0o600))
+
+	multiline := filepath.Join(dir, "multiline")
+	require.NoError(t, os.WriteFile(multiline, []byte("line1\nline2\n\n"), 0o600))
+
+	t.Setenv("PRIVATEBIN_TEST_SECRET", "from-env\n")
+
+	tests := []struct {
+		name    string
+		secret  Secret
+		want    string
+		wantErr string
+	}{
+		{name: "inline", secret: Secret{Value: "inline\n"}, want: "inline\n"},
+		{name: "env kept as is", secret: Secret{Env: "PRIVATEBIN_TEST_SECRET"}, want: "from-env\n"},
+		{name: "missing env", secret: Secret{Env: "PRIVATEBIN_TEST_UNSET"}, wantErr: "environment variable PRIVATEBIN_TEST_UNSET is not set"},
+		{name: "file", secret: Secret{File: file}, want: "from-file"},
+		{name: "file with crlf", secret: Secret{File: crlfFile}, want: "from-file"},
+		{name: "only the last newline is trimmed", secret: Secret{File: multiline}, want: "line1\nline2\n"},
+		{name: "missing file", secret: Secret{File: filepath.Join(dir, "missing")}, wantErr: "cannot read secret file"},
+		{name: "unreadable file", secret: Secret{File: dir}, wantErr: "cannot read secret file"},
+		{name: "command", secret: Secret{Command: []string{"sh", "-c", "echo from-command"}}, want: "from-command"},
+		{name: "failing command", secret: Secret{Command: []string{"sh", "-c", "echo partial; exit 3"}}, wantErr: "cannot run secret command \"sh\""},
+		{name: "missing command", secret: Secret{Command: []string{filepath.Join(dir, "missing")}}, wantErr: "cannot run secret command"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.secret.Resolve()
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "~", want: home},
+		{path: "~/token", want: filepath.Join(home, "token")},
+		{path: "~user/token", want: "~user/token"},
+		{path: "/etc/token", want: "/etc/token"},
+		{path: "token", want: "token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := expandHome(tt.path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWarnReadableByOthers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not checked on windows")
+	}
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		mode     os.FileMode
+		wantWarn bool
+	}{
+		{name: "owner only", mode: 0o600},
+		{name: "group readable", mode: 0o640, wantWarn: true},
+		{name: "world readable", mode: 0o604, wantWarn: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			require.NoError(t, os.WriteFile(path, []byte("secret"), 0o600))
+			require.NoError(t, os.Chmod(path, tt.mode))
+
+			out := captureStderr(t, func() { warnReadableByOthers(path, "secret file") })
+			if !tt.wantWarn {
+				assert.Empty(t, out)
+				return
+			}
+
+			assert.Contains(t, out, "warning: secret file "+path+" is readable by other users")
+			assert.Contains(t, out, "chmod 600 "+path)
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		out := captureStderr(t, func() { warnReadableByOthers(filepath.Join(dir, "missing"), "secret file") })
+		assert.Empty(t, out)
+	})
+}
+
+func TestCfg_HasInlineSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Cfg
+		want bool
+	}{
+		{name: "empty", cfg: Cfg{}},
+		{
+			name: "external sources only",
+			cfg: Cfg{
+				ExtraHeaderFields: map[string]Secret{"X-Token": {Env: "TOKEN"}},
+				Bin: []BinCfg{
+					{
+						Auth:              AuthCfg{Username: "user", Password: Secret{File: "password"}},
+						ExtraHeaderFields: map[string]Secret{"X-Token": {Command: []string{"pass"}}},
+					},
+				},
+			},
+		},
+		{
+			name: "inline global header field",
+			cfg:  Cfg{ExtraHeaderFields: map[string]Secret{"X-Token": {Value: "token"}}},
+			want: true,
+		},
+		{
+			name: "inline bin password",
+			cfg:  Cfg{Bin: []BinCfg{{Auth: AuthCfg{Password: Secret{Value: "password"}}}}},
+			want: true,
+		},
+		{
+			name: "inline bin header field",
+			cfg:  Cfg{Bin: []BinCfg{{ExtraHeaderFields: map[string]Secret{"X-Token": {Value: "token"}}}}},
+			want: true,
+		},
+		{
+			name: "empty inline value",
+			cfg:  Cfg{Bin: []BinCfg{{Auth: AuthCfg{Password: Secret{}}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cfg.hasInlineSecrets())
+		})
+	}
+}
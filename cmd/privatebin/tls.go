@@ -0,0 +1,147 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig builds the TLS configuration of the bin, it returns nil
+// when the bin uses the defaults.
+func newTLSConfig(bin *BinCfg) (*tls.Config, error) {
+	skipVerify := bin.SkipTLSVerify != nil && *bin.SkipTLSVerify
+
+	if !skipVerify && bin.CAFile == "" && bin.ClientCert == "" && bin.ClientKey == "" &&
+		bin.MinTLSVersion == "" && len(bin.Pins) == 0 {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+	}
+
+	if bin.CAFile != "" {
+		pem, err := os.ReadFile(bin.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("cannot load ca file %q: no PEM certificate found", bin.CAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if bin.ClientCert != "" || bin.ClientKey != "" {
+		if bin.ClientCert == "" || bin.ClientKey == "" {
+			return nil, fmt.Errorf("client-cert and client-key must be set together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(bin.ClientCert, bin.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if bin.MinTLSVersion != "" {
+		version, ok := tlsVersions[bin.MinTLSVersion]
+		if !ok {
+			return nil, fmt.Errorf("invalid min-tls-version: %q, valid options are '1.0', '1.1', '1.2', '1.3'", bin.MinTLSVersion)
+		}
+
+		tlsConfig.MinVersion = version
+	}
+
+	if len(bin.Pins) > 0 {
+		pins := make([][]byte, 0, len(bin.Pins))
+		for _, pin := range bin.Pins {
+			hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+			if err != nil || len(hash) != sha256.Size {
+				return nil, fmt.Errorf("invalid pin %q: expected a base64 SHA-256 hash, optionally prefixed with \"sha256/\"", pin)
+			}
+
+			pins = append(pins, hash)
+		}
+
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(cs, pins, skipVerify)
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// verifyPins checks that the SPKI SHA-256 hash of one of the certificates
+// of the verified chains is pinned. The server can send any certificate
+// along its own, so unverified certificates are never matched: when
+// verification is skipped, only the leaf certificate is.
+func verifyPins(cs tls.ConnectionState, pins [][]byte, skipVerify bool) error {
+	server := "the server"
+	if cs.ServerName != "" {
+		server = cs.ServerName
+	}
+
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("certificate pin mismatch: %s presented no certificate", server)
+	}
+
+	var candidates []*x509.Certificate
+	if skipVerify {
+		candidates = cs.PeerCertificates[:1]
+	} else {
+		for _, chain := range cs.VerifiedChains {
+			candidates = append(candidates, chain...)
+		}
+	}
+
+	for _, cert := range candidates {
+		hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(hash[:], pin) {
+				return nil
+			}
+		}
+	}
+
+	leaf := sha256.Sum256(cs.PeerCertificates[0].RawSubjectPublicKeyInfo)
+
+	return fmt.Errorf(
+		"certificate pin mismatch: no verified certificate of %s matches the configured pins, its certificate pin is sha256/%s",
+		server,
+		base64.StdEncoding.EncodeToString(leaf[:]),
+	)
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCert(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+func pinOf(cert *x509.Certificate) string {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func pinHashes(t *testing.T, pins ...string) [][]byte {
+	t.Helper()
+
+	hashes := make([][]byte, 0, len(pins))
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin[len("sha256/"):])
+		require.NoError(t, err)
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+func TestNewTLSConfig_Pins(t *testing.T) {
+	cert, _ := newTestCert(t, "example.com", true, nil, nil)
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	tests := []struct {
+		name    string
+		pins    []string
+		wantErr bool
+	}{
+		{name: "Prefixed pin", pins: []string{pinOf(cert)}},
+		{name: "Bare pin", pins: []string{base64.StdEncoding.EncodeToString(hash[:])}},
+		{name: "Invalid base64", pins: []string{"sha256/not base64"}, wantErr: true},
+		{name: "Wrong hash size", pins: []string{"sha256/" + base64.StdEncoding.EncodeToString(hash[:16])}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(&BinCfg{Pins: tt.pins})
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, tlsConfig.VerifyConnection)
+
+			cs := tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert}},
+			}
+			assert.NoError(t, tlsConfig.VerifyConnection(cs))
+		})
+	}
+}
+
+func TestNewTLSConfig_Defaults(t *testing.T) {
+	tlsConfig, err := newTLSConfig(&BinCfg{})
+	require.NoError(t, err)
+	assert.Nil(t, tlsConfig)
+
+	_, err = newTLSConfig(&BinCfg{MinTLSVersion: "1.4"})
+	assert.Error(t, err)
+}
+
+func TestVerifyPins(t *testing.T) {
+	root, rootKey := newTestCert(t, "root", true, nil, nil)
+	intermediate, intermediateKey := newTestCert(t, "intermediate", true, root, rootKey)
+	leaf, _ := newTestCert(t, "example.com", false, intermediate, intermediateKey)
+	pinned, _ := newTestCert(t, "pinned.example.com", false, intermediate, intermediateKey)
+	selfSigned, _ := newTestCert(t, "example.com", false, nil, nil)
+
+	chain := []*x509.Certificate{leaf, intermediate, root}
+
+	tests := []struct {
+		name       string
+		cs         tls.ConnectionState
+		pins       []string
+		skipVerify bool
+		wantErr    string
+	}{
+		{
+			name: "Leaf pinned",
+			cs:   tls.ConnectionState{PeerCertificates: chain[:2], VerifiedChains: [][]*x509.Certificate{chain}},
+			pins: []string{pinOf(leaf)},
+		},
+		{
+			name: "Intermediate pinned",
+			cs:   tls.ConnectionState{PeerCertificates: chain[:2], VerifiedChains: [][]*x509.Certificate{chain}},
+			pins: []string{pinOf(intermediate)},
+		},
+		{
+			name:    "Mismatch",
+			cs:      tls.ConnectionState{ServerName: "example.com", PeerCertificates: chain[:2], VerifiedChains: [][]*x509.Certificate{chain}},
+			pins:    []string{pinOf(pinned)},
+			wantErr: "certificate pin mismatch: no verified certificate of example.com matches the configured pins, its certificate pin is " + pinOf(leaf),
+		},
+		{
+			name: "Appended certificate outside the verified chain",
+			cs: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{leaf, intermediate, pinned},
+				VerifiedChains:   [][]*x509.Certificate{chain},
+			},
+			pins:    []string{pinOf(pinned)},
+			wantErr: "certificate pin mismatch",
+		},
+		{
+			name:    "No verified chain",
+			cs:      tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf, pinned}},
+			pins:    []string{pinOf(pinned)},
+			wantErr: "certificate pin mismatch",
+		},
+		{
+			name:       "Skip verify with pinned leaf",
+			cs:         tls.ConnectionState{PeerCertificates: []*x509.Certificate{selfSigned}},
+			pins:       []string{pinOf(selfSigned)},
+			skipVerify: true,
+		},
+		{
+			name:       "Skip verify with appended pinned certificate",
+			cs:         tls.ConnectionState{PeerCertificates: []*x509.Certificate{selfSigned, pinned}},
+			pins:       []string{pinOf(pinned)},
+			skipVerify: true,
+			wantErr:    "certificate pin mismatch",
+		},
+		{
+			name:    "No certificate",
+			cs:      tls.ConnectionState{},
+			pins:    []string{pinOf(leaf)},
+			wantErr: "presented no certificate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyPins(tt.cs, pinHashes(t, tt.pins...), tt.skipVerify)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestNewTLSConfig_Server(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(ts.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(
+		caFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}),
+		0o600,
+	))
+
+	other, _ := newTestCert(t, "example.com", false, nil, nil)
+
+	tests := []struct {
+		name    string
+		pin     string
+		wantErr bool
+	}{
+		{name: "Pinned", pin: pinOf(ts.Certificate())},
+		{name: "Not pinned", pin: pinOf(other), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(&BinCfg{CAFile: caFile, Pins: []string{tt.pin}})
+			require.NoError(t, err)
+
+			httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			res, err := httpClient.Get(ts.URL)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "certificate pin mismatch")
+				return
+			}
+
+			require.NoError(t, err)
+			_ = res.Body.Close()
+		})
+	}
+}
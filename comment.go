@@ -0,0 +1,59 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+type (
+	// CommentNode is a comment along with its replies.
+	CommentNode struct {
+		Comment Comment
+		Replies []*CommentNode
+	}
+)
+
+// BuildCommentTree arranges comments as a discussion thread. Comments whose
+// parent is the paste are returned as roots; replies are attached to their
+// parent comment. A reply is only attached to a parent listed before it,
+// like servers do, so that a hostile server cannot create cycles; comments
+// referencing an unknown or later parent are kept as roots so that none is
+// lost. The order of the comments is preserved at every level.
+func BuildCommentTree(pasteID string, comments []Comment) []*CommentNode {
+	var (
+		roots []*CommentNode
+		seen  = make(map[string]*CommentNode, len(comments))
+	)
+
+	for _, comment := range comments {
+		node := &CommentNode{Comment: comment}
+
+		parent, ok := seen[comment.ParentID]
+		if comment.ParentID == pasteID || !ok {
+			roots = append(roots, node)
+		} else {
+			parent.Replies = append(parent.Replies, node)
+		}
+
+		if _, ok := seen[comment.CommentID]; !ok {
+			seen[comment.CommentID] = node
+		}
+	}
+
+	return roots
+}
+
+// CommentTree returns the comments of the paste arranged as a discussion
+// thread, see BuildCommentTree.
+func (r *ShowPasteResult) CommentTree() []*CommentNode {
+	return BuildCommentTree(r.PasteID, r.Comments)
+}
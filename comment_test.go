@@ -0,0 +1,102 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	type node struct {
+		id      string
+		replies []node
+	}
+
+	var flatten func(nodes []*CommentNode) []node
+	flatten = func(nodes []*CommentNode) []node {
+		var out []node
+		for _, n := range nodes {
+			out = append(out, node{n.Comment.CommentID, flatten(n.Replies)})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		comments []Comment
+		want     []node
+	}{
+		{
+			name: "No comment",
+			want: nil,
+		},
+		{
+			name: "Flat discussion",
+			comments: []Comment{
+				{CommentID: "a", ParentID: "paste"},
+				{CommentID: "b", ParentID: "paste"},
+			},
+			want: []node{{id: "a"}, {id: "b"}},
+		},
+		{
+			name: "Nested replies",
+			comments: []Comment{
+				{CommentID: "a", ParentID: "paste"},
+				{CommentID: "b", ParentID: "a"},
+				{CommentID: "c", ParentID: "paste"},
+				{CommentID: "d", ParentID: "b"},
+				{CommentID: "e", ParentID: "a"},
+			},
+			want: []node{
+				{id: "a", replies: []node{
+					{id: "b", replies: []node{{id: "d"}}},
+					{id: "e"},
+				}},
+				{id: "c"},
+			},
+		},
+		{
+			name: "Unknown parent",
+			comments: []Comment{
+				{CommentID: "a", ParentID: "missing"},
+			},
+			want: []node{{id: "a"}},
+		},
+		{
+			name: "Cycle",
+			comments: []Comment{
+				{CommentID: "a", ParentID: "b"},
+				{CommentID: "b", ParentID: "a"},
+			},
+			want: []node{{id: "a", replies: []node{{id: "b"}}}},
+		},
+		{
+			name: "Self reference",
+			comments: []Comment{
+				{CommentID: "a", ParentID: "a"},
+			},
+			want: []node{{id: "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildCommentTree("paste", tt.comments)
+			assert.Equal(t, tt.want, flatten(got))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	msgPasteNotFound      = "Paste does not exist, has expired or has been deleted."
+	msgInvalidDeleteToken = "Wrong deletion token. Paste was not deleted."
+)
+
+var (
+	// ErrPasteNotFound is returned when the server reports that the paste
+	// does not exist, has expired or has already been deleted. PrivateBin
+	// answers with the same message in all three cases, an expired paste
+	// cannot be told apart from a missing one.
+	ErrPasteNotFound = errors.New("paste does not exist, has expired or has been deleted")
+
+	// ErrInvalidDeleteToken is returned when the server refuses to delete a
+	// paste because the delete token does not match.
+	ErrInvalidDeleteToken = errors.New("invalid delete token")
+
+	// ErrDecryptionFailed is returned when the cipher text cannot be
+	// authenticated with the derived key.
+	ErrDecryptionFailed = errors.New("wrong master key or password")
+
+	// ErrWrongPassword is an alias of ErrDecryptionFailed: AES-GCM cannot
+	// tell a wrong password apart from a wrong master key or a tampered
+	// cipher text.
+	ErrWrongPassword = ErrDecryptionFailed
+
+	// ErrBurnNotConfirmed is returned when reading a burn after reading
+	// paste without ShowPasteOptions.ConfirmBurn.
+	ErrBurnNotConfirmed = errors.New("cannot read a paste that is set to be burned after reading")
+
+	// ErrRateLimited is returned when the server traffic limiter rejects
+	// the request. Use errors.As with *ServerError to get the delay
+	// requested by the server.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrPasteTooLarge is returned by CreatePasteFromReader when the input
+	// is larger than CreatePasteOptions.MaxSize.
+	ErrPasteTooLarge = errors.New("paste is too large")
+
+	rateLimitRegexp = regexp.MustCompile(`(?i)wait (\d+) seconds?`)
+)
+
+type (
+	// ServerError is returned when the server answers with a non-zero
+	// PrivateBin status, an unexpected HTTP status code or a body that is
+	// not a PrivateBin JSON response.
+	ServerError struct {
+		// HTTPStatus is the HTTP status code of the response.
+		HTTPStatus int
+
+		// Status is the PrivateBin status of the response, zero when the
+		// response body could not be decoded.
+		Status int
+
+		// Message is the PrivateBin error message, or a description of
+		// the HTTP failure.
+		Message string
+
+		// RetryAfter is the delay requested by the server before sending
+		// another request, zero when unknown.
+		RetryAfter time.Duration
+	}
+)
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("server respond with %d status (HTTP %d): %s", e.Status, e.HTTPStatus, e.Message)
+}
+
+func (e *ServerError) Is(target error) bool {
+	switch target {
+	case ErrPasteNotFound:
+		return e.Message == msgPasteNotFound
+	case ErrInvalidDeleteToken:
+		return e.Message == msgInvalidDeleteToken
+	case ErrRateLimited:
+		return e.HTTPStatus == http.StatusTooManyRequests || rateLimitRegexp.MatchString(e.Message)
+	}
+
+	return false
+}
+
+func newServerError(res *http.Response, status int, message string) *ServerError {
+	err := &ServerError{
+		HTTPStatus: res.StatusCode,
+		Status:     status,
+		Message:    message,
+	}
+
+	if err.Message == "" {
+		err.Message = http.StatusText(res.StatusCode)
+	}
+
+	if v := res.Header.Get("Retry-After"); v != "" {
+		err.RetryAfter = parseRetryAfter(v)
+	}
+
+	if m := rateLimitRegexp.FindStringSubmatch(message); m != nil {
+		seconds, _ := strconv.Atoi(m[1])
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return err
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number
+// of seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
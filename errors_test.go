@@ -0,0 +1,186 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerError_Is(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     *ServerError
+		targets []error
+		notIn   []error
+	}{
+		{
+			name:    "Paste not found",
+			err:     &ServerError{HTTPStatus: 200, Status: 1, Message: "Paste does not exist, has expired or has been deleted."},
+			targets: []error{ErrPasteNotFound},
+			notIn:   []error{ErrInvalidDeleteToken, ErrRateLimited},
+		},
+		{
+			name:    "Wrong delete token",
+			err:     &ServerError{HTTPStatus: 200, Status: 1, Message: "Wrong deletion token. Paste was not deleted."},
+			targets: []error{ErrInvalidDeleteToken},
+			notIn:   []error{ErrPasteNotFound, ErrRateLimited},
+		},
+		{
+			name:    "Traffic limiter",
+			err:     &ServerError{HTTPStatus: 200, Status: 1, Message: "Please wait 10 seconds between each post."},
+			targets: []error{ErrRateLimited},
+			notIn:   []error{ErrPasteNotFound},
+		},
+		{
+			name:    "Too many requests",
+			err:     &ServerError{HTTPStatus: 429, Message: "Too Many Requests"},
+			targets: []error{ErrRateLimited},
+		},
+		{
+			name:  "Other error",
+			err:   &ServerError{HTTPStatus: 500, Message: "Internal Server Error"},
+			notIn: []error{ErrPasteNotFound, ErrInvalidDeleteToken, ErrRateLimited},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, target := range tt.targets {
+				assert.ErrorIs(t, tt.err, target)
+			}
+
+			for _, target := range tt.notIn {
+				assert.NotErrorIs(t, tt.err, target)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  time.Duration
+	}{
+		{name: "Seconds", input: "120", want: 2 * time.Minute},
+		{name: "Seconds with spaces", input: " 5 ", want: 5 * time.Second},
+		{name: "Past date", input: "Wed, 21 Oct 2015 07:28:00 GMT", want: 0},
+		{name: "Garbage", input: "soon", want: 0},
+		{name: "Negative", input: "-1", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseRetryAfter(tt.input))
+		})
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           int
+		header         http.Header
+		body           string
+		wantErr        bool
+		wantHTTPStatus int
+		wantStatus     int
+		wantRetryAfter time.Duration
+	}{
+		{
+			name: "Success",
+			code: 200,
+			body: `{"status":0,"id":"f468483c313401e8"}`,
+		},
+		{
+			name:           "PrivateBin error",
+			code:           200,
+			body:           `{"status":1,"message":"Invalid data."}`,
+			wantErr:        true,
+			wantHTTPStatus: 200,
+			wantStatus:     1,
+		},
+		{
+			name:           "Traffic limiter",
+			code:           200,
+			body:           `{"status":1,"message":"Please wait 15 seconds between each post."}`,
+			wantErr:        true,
+			wantHTTPStatus: 200,
+			wantStatus:     1,
+			wantRetryAfter: 15 * time.Second,
+		},
+		{
+			name:           "Internal server error",
+			code:           500,
+			body:           "Internal Server Error",
+			wantErr:        true,
+			wantHTTPStatus: 500,
+		},
+		{
+			name:           "Too many requests with retry after header",
+			code:           429,
+			header:         http.Header{"Retry-After": []string{"30"}},
+			body:           "slow down",
+			wantErr:        true,
+			wantHTTPStatus: 429,
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:           "HTML page",
+			code:           200,
+			body:           "<!DOCTYPE html><html></html>",
+			wantErr:        true,
+			wantHTTPStatus: 200,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+
+			res := &http.Response{
+				StatusCode: tt.code,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			var v createPasteResponse
+			err := decodeResponse(res, &v, 0)
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, "f468483c313401e8", v.ID)
+				return
+			}
+
+			var serverErr *ServerError
+			require.True(t, errors.As(err, &serverErr))
+			assert.Equal(t, tt.wantHTTPStatus, serverErr.HTTPStatus)
+			assert.Equal(t, tt.wantStatus, serverErr.Status)
+			assert.Equal(t, tt.wantRetryAfter, serverErr.RetryAfter)
+			assert.NotEmpty(t, serverErr.Message)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	ExpireUnknown Expire = iota
+	Expire5Min
+	Expire10Min
+	Expire1Hour
+	Expire1Day
+	Expire1Week
+	Expire1Month
+	Expire1Year
+	ExpireNever
+)
+
+// Expire is the time to live of a paste, among the options supported by
+// PrivateBin. The zero value is unset, pastes are then created with the
+// default option of the instance.
+type Expire uint8
+
+// ParseExpire parses "5min", "10min", "1hour", "1day", "1week",
+// "1month", "1year" or "never".
+func ParseExpire(s string) (Expire, error) {
+	for e := Expire5Min; e <= ExpireNever; e++ {
+		if e.String() == s {
+			return e, nil
+		}
+	}
+
+	return ExpireUnknown, fmt.Errorf("invalid expire: %q, valid options are '5min', '10min', '1hour', '1day', '1week', '1month', '1year', 'never'", s)
+}
+
+// NearestExpire returns the option closest to d, the shortest one on a
+// tie. It never returns ExpireNever: durations above one year map to
+// Expire1Year and non-positive ones to Expire5Min.
+func NearestExpire(d time.Duration) Expire {
+	nearest := Expire5Min
+	for e := Expire5Min; e < ExpireNever; e++ {
+		if (d - e.Duration()).Abs() < (d - nearest.Duration()).Abs() {
+			nearest = e
+		}
+	}
+
+	return nearest
+}
+
+// MarshalJSON encodes the option name, an empty string when unset.
+func (e Expire) MarshalJSON() ([]byte, error) {
+	if e == ExpireUnknown {
+		return json.Marshal("")
+	}
+
+	return json.Marshal(e.String())
+}
+
+// UnmarshalJSON decodes an expire option, an empty string leaves it
+// unset.
+func (e *Expire) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if s == "" {
+		*e = ExpireUnknown
+		return nil
+	}
+
+	v, err := ParseExpire(s)
+	if err != nil {
+		return err
+	}
+
+	*e = v
+
+	return nil
+}
+
+func (e Expire) String() string {
+	switch e {
+	case Expire5Min:
+		return "5min"
+	case Expire10Min:
+		return "10min"
+	case Expire1Hour:
+		return "1hour"
+	case Expire1Day:
+		return "1day"
+	case Expire1Week:
+		return "1week"
+	case Expire1Month:
+		return "1month"
+	case Expire1Year:
+		return "1year"
+	case ExpireNever:
+		return "never"
+	default:
+		return "unknown"
+	}
+}
+
+// Duration returns the time to live of the option, as computed by
+// PrivateBin: a month is 30 days and a year 365 days. It is zero for
+// ExpireNever and unset values.
+func (e Expire) Duration() time.Duration {
+	switch e {
+	case Expire5Min:
+		return 5 * time.Minute
+	case Expire10Min:
+		return 10 * time.Minute
+	case Expire1Hour:
+		return time.Hour
+	case Expire1Day:
+		return 24 * time.Hour
+	case Expire1Week:
+		return 7 * 24 * time.Hour
+	case Expire1Month:
+		return 30 * 24 * time.Hour
+	case Expire1Year:
+		return 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// metaValue returns the expire option sent to the server, empty when
+// unset so the server applies its default.
+func (e Expire) metaValue() (string, error) {
+	switch {
+	case e == ExpireUnknown:
+		return "", nil
+	case e <= ExpireNever:
+		return e.String(), nil
+	default:
+		return "", fmt.Errorf("invalid expire: %d", e)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpire(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Expire
+		wantErr bool
+	}{
+		{name: "Five minutes", input: "5min", want: Expire5Min},
+		{name: "Ten minutes", input: "10min", want: Expire10Min},
+		{name: "One hour", input: "1hour", want: Expire1Hour},
+		{name: "One day", input: "1day", want: Expire1Day},
+		{name: "One week", input: "1week", want: Expire1Week},
+		{name: "One month", input: "1month", want: Expire1Month},
+		{name: "One year", input: "1year", want: Expire1Year},
+		{name: "Never", input: "never", want: ExpireNever},
+		{name: "Plural", input: "1days", wantErr: true},
+		{name: "Duration", input: "24h", wantErr: true},
+		{name: "Empty", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExpire(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.input, got.String())
+		})
+	}
+}
+
+func TestExpire_JSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		expire Expire
+		want   string
+	}{
+		{name: "Unset", expire: ExpireUnknown, want: `""`},
+		{name: "One day", expire: Expire1Day, want: `"1day"`},
+		{name: "Never", expire: ExpireNever, want: `"never"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.expire)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+
+			var got Expire
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, tt.expire, got)
+		})
+	}
+
+	t.Run("Invalid value", func(t *testing.T) {
+		var got Expire
+		assert.Error(t, json.Unmarshal([]byte(`"2days"`), &got))
+		assert.Error(t, json.Unmarshal([]byte(`3600`), &got))
+	})
+}
+
+func TestExpire_Duration(t *testing.T) {
+	assert.Equal(t, 5*time.Minute, Expire5Min.Duration())
+	assert.Equal(t, 30*24*time.Hour, Expire1Month.Duration())
+	assert.Equal(t, 365*24*time.Hour, Expire1Year.Duration())
+	assert.Zero(t, ExpireNever.Duration())
+	assert.Zero(t, ExpireUnknown.Duration())
+}
+
+func TestNearestExpire(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		want     Expire
+	}{
+		{name: "Negative", duration: -time.Hour, want: Expire5Min},
+		{name: "Zero", duration: 0, want: Expire5Min},
+		{name: "Exact option", duration: 24 * time.Hour, want: Expire1Day},
+		{name: "Rounded down", duration: 2 * time.Hour, want: Expire1Hour},
+		{name: "Rounded up", duration: 20 * time.Hour, want: Expire1Day},
+		{name: "Tie goes to shorter", duration: 7*time.Minute + 30*time.Second, want: Expire5Min},
+		{name: "Three days", duration: 3 * 24 * time.Hour, want: Expire1Day},
+		{name: "Five days", duration: 5 * 24 * time.Hour, want: Expire1Week},
+		{name: "Beyond one year", duration: 10 * 365 * 24 * time.Hour, want: Expire1Year},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NearestExpire(tt.duration))
+		})
+	}
+}
+
+func TestSeal_Expire(t *testing.T) {
+	encryptedPaste, _, err := Seal(Paste{Data: []byte("hello")}, SealOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, encryptedPaste.Meta.Expire)
+	assert.Equal(t, "plaintext", encryptedPaste.AData.Formatter)
+
+	_, _, err = Seal(Paste{Data: []byte("hello")}, SealOptions{Expire: Expire(42)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	FormatterUnknown Formatter = iota
+	FormatterPlainText
+	FormatterMarkdown
+	FormatterSyntaxHighlighting
+)
+
+// Formatter is how PrivateBin renders the paste text. The zero value is
+// unset, pastes are then created with FormatterPlainText.
+type Formatter uint8
+
+// ParseFormatter parses "plaintext", "markdown" or "syntaxhighlighting".
+func ParseFormatter(s string) (Formatter, error) {
+	for f := FormatterPlainText; f <= FormatterSyntaxHighlighting; f++ {
+		if f.String() == s {
+			return f, nil
+		}
+	}
+
+	return FormatterUnknown, fmt.Errorf("invalid formatter: %q, valid options are 'plaintext', 'markdown', 'syntaxhighlighting'", s)
+}
+
+// MarshalJSON encodes the option name, an empty string when unset.
+func (f Formatter) MarshalJSON() ([]byte, error) {
+	if f == FormatterUnknown {
+		return json.Marshal("")
+	}
+
+	return json.Marshal(f.String())
+}
+
+// UnmarshalJSON decodes a formatter name, an empty string leaves the
+// formatter unset.
+func (f *Formatter) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if s == "" {
+		*f = FormatterUnknown
+		return nil
+	}
+
+	v, err := ParseFormatter(s)
+	if err != nil {
+		return err
+	}
+
+	*f = v
+
+	return nil
+}
+
+func (f Formatter) String() string {
+	switch f {
+	case FormatterPlainText:
+		return "plaintext"
+	case FormatterMarkdown:
+		return "markdown"
+	case FormatterSyntaxHighlighting:
+		return "syntaxhighlighting"
+	default:
+		return "unknown"
+	}
+}
+
+// adataValue returns the formatter written in the authenticated data.
+func (f Formatter) adataValue() (string, error) {
+	switch f {
+	case FormatterUnknown:
+		return FormatterPlainText.String(), nil
+	case FormatterPlainText, FormatterMarkdown, FormatterSyntaxHighlighting:
+		return f.String(), nil
+	default:
+		return "", fmt.Errorf("invalid formatter: %d", f)
+	}
+}
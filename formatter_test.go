@@ -0,0 +1,89 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFormatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    Formatter
+		wantErr bool
+	}{
+		{name: "Plain text", input: "plaintext", want: FormatterPlainText},
+		{name: "Markdown", input: "markdown", want: FormatterMarkdown},
+		{name: "Syntax highlighting", input: "syntaxhighlighting", want: FormatterSyntaxHighlighting},
+		{name: "Unknown", input: "unknown", wantErr: true},
+		{name: "Wrong case", input: "Markdown", wantErr: true},
+		{name: "Empty", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFormatter(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.input, got.String())
+		})
+	}
+}
+
+func TestFormatter_JSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatter Formatter
+		want      string
+	}{
+		{name: "Unset", formatter: FormatterUnknown, want: `""`},
+		{name: "Plain text", formatter: FormatterPlainText, want: `"plaintext"`},
+		{name: "Markdown", formatter: FormatterMarkdown, want: `"markdown"`},
+		{name: "Syntax highlighting", formatter: FormatterSyntaxHighlighting, want: `"syntaxhighlighting"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.formatter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+
+			var got Formatter
+			require.NoError(t, json.Unmarshal(data, &got))
+			assert.Equal(t, tt.formatter, got)
+		})
+	}
+
+	t.Run("Invalid value", func(t *testing.T) {
+		var got Formatter
+		assert.Error(t, json.Unmarshal([]byte(`"html"`), &got))
+		assert.Error(t, json.Unmarshal([]byte(`1`), &got))
+	})
+}
+
+func TestSeal_InvalidFormatter(t *testing.T) {
+	_, _, err := Seal(Paste{Data: []byte("hello")}, SealOptions{Formatter: Formatter(42)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	redacted = "REDACTED"
+)
+
+var (
+	discardLogger = slog.New(slog.DiscardHandler)
+
+	// alwaysSensitiveHeaderFields are redacted whatever the options, they
+	// carry the basic auth credentials and session cookies.
+	alwaysSensitiveHeaderFields = []string{
+		"Authorization",
+		"Proxy-Authorization",
+		"Cookie",
+	}
+)
+
+// WithLogger sets the logger of the client. Requests and responses are
+// logged at the info level, the request headers and the duration of the
+// key derivation, compression and encryption steps at the debug level.
+// The URL fragment holding the master key, the credentials, the passwords
+// and the values of the sensitive header fields are never logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithSensitiveHeaderField is like WithCustomHeaderField but the value is
+// redacted from the logs.
+func WithSensitiveHeaderField(k, v string) Option {
+	return func(c *Client) {
+		c.customHTTPHeaderFields[k] = v
+		c.sensitiveHTTPHeaderFields[http.CanonicalHeaderKey(k)] = true
+	}
+}
+
+func logDuration(logger *slog.Logger, msg string, start time.Time, args ...any) {
+	logger.Debug(msg, append([]any{"duration", time.Since(start)}, args...)...)
+}
+
+// redactURL returns u without the fragment and the user information.
+func redactURL(u *url.URL) string {
+	r := *u
+
+	if r.Fragment != "" || r.RawFragment != "" {
+		r.Fragment = redacted
+		r.RawFragment = ""
+	}
+
+	if r.User != nil {
+		r.User = url.User(redacted)
+	}
+
+	return r.String()
+}
+
+// redactHeader returns a copy of h with the values of the sensitive
+// fields replaced.
+func (c *Client) redactHeader(h http.Header) http.Header {
+	r := h.Clone()
+
+	for _, k := range alwaysSensitiveHeaderFields {
+		if _, ok := r[k]; ok {
+			r[k] = []string{redacted}
+		}
+	}
+
+	for k := range c.sensitiveHTTPHeaderFields {
+		if _, ok := r[k]; ok {
+			r[k] = []string{redacted}
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"fmt"
+)
+
+const (
+	// DefaultPasswordLength is the length of the passwords generated by
+	// GeneratePassword, about 140 bits of entropy.
+	DefaultPasswordLength = 24
+
+	// MinPasswordLength is the shortest password GeneratePassword
+	// accepts to generate, about 93 bits of entropy.
+	MinPasswordLength = 16
+
+	// passwordAlphabet is the base58 alphabet, it leaves out the
+	// characters that look alike (0, O, I and l).
+	passwordAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+// GeneratePassword returns a random password of length characters drawn
+// uniformly from the base58 alphabet, suited to be shared on another
+// channel than the paste URL. A zero length means DefaultPasswordLength.
+func GeneratePassword(length int) ([]byte, error) {
+	if length == 0 {
+		length = DefaultPasswordLength
+	}
+
+	if length < MinPasswordLength {
+		return nil, fmt.Errorf("invalid password length: %d, must be at least %d", length, MinPasswordLength)
+	}
+
+	// Bytes above the largest multiple of the alphabet size are
+	// rejected to keep the distribution uniform.
+	limit := byte(256 - 256%len(passwordAlphabet))
+
+	password := make([]byte, 0, length)
+	for len(password) < length {
+		b, err := generateRandomBytes(uint32(length - len(password)))
+		if err != nil {
+			return nil, fmt.Errorf("cannot generate random bytes: %w", err)
+		}
+
+		for _, c := range b {
+			if c < limit {
+				password = append(password, passwordAlphabet[int(c)%len(passwordAlphabet)])
+			}
+		}
+	}
+
+	return password, nil
+}
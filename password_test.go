@@ -0,0 +1,60 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		length     int
+		wantLength int
+		wantErr    bool
+	}{
+		{name: "Default length", length: 0, wantLength: DefaultPasswordLength},
+		{name: "Minimum length", length: MinPasswordLength, wantLength: MinPasswordLength},
+		{name: "Long password", length: 64, wantLength: 64},
+		{name: "Too short", length: MinPasswordLength - 1, wantErr: true},
+		{name: "Negative length", length: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			password, err := GeneratePassword(tt.length)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, password, tt.wantLength)
+			for _, c := range password {
+				assert.Contains(t, passwordAlphabet, string(c))
+			}
+		})
+	}
+
+	a, err := GeneratePassword(0)
+	require.NoError(t, err)
+	b, err := GeneratePassword(0)
+	require.NoError(t, err)
+	assert.False(t, bytes.Equal(a, b))
+}
@@ -23,39 +23,72 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"unicode"
 )
 
 type (
 	Paste struct {
-		Data           []byte
+		Data []byte
+
+		// Deprecated: use Attachments. Decoded pastes carry their first
+		// attachment in these fields too. They are only encoded when
+		// Attachments is empty.
 		Attachment     []byte
 		AttachmentName string
 		MimeType       string
+
+		Attachments []Attachment
+	}
+
+	Attachment struct {
+		Name string
+
+		// MimeType is inferred from the name extension when empty.
+		MimeType string
+
+		Data []byte
 	}
 )
 
+// MarshalJSON encodes a single attachment with the legacy string fields
+// understood by every PrivateBin version, and several attachments with the
+// array fields introduced by PrivateBin 2.0.
 func (p Paste) MarshalJSON() ([]byte, error) {
-	output := map[string]string{}
-
-	if len(p.Attachment) > 0 {
-		mimeType := p.MimeType
-		if mimeType == "" {
-			ext := filepath.Ext(p.AttachmentName)
-			mimeType = mime.TypeByExtension(ext)
-			if mimeType == "" {
-				mimeType = "application/octet-stream"
-			}
+	output := map[string]any{}
+
+	attachments := p.Attachments
+	if len(attachments) == 0 && len(p.Attachment) > 0 {
+		attachments = []Attachment{
+			{
+				Name:     p.AttachmentName,
+				MimeType: p.MimeType,
+				Data:     p.Attachment,
+			},
 		}
+	}
 
-		if p.AttachmentName != "" {
-			output["attachment_name"] = p.AttachmentName
+	switch len(attachments) {
+	case 0:
+	case 1:
+		attachment := attachments[0]
+		if attachment.Name != "" {
+			output["attachment_name"] = attachment.Name
 		}
 
-		output["attachment"] = fmt.Sprintf(
-			"data:%s;base64,%s",
-			mimeType,
-			base64.StdEncoding.EncodeToString(p.Attachment),
+		output["attachment"] = attachment.dataURL()
+	default:
+		var (
+			names = make([]string, len(attachments))
+			urls  = make([]string, len(attachments))
 		)
+
+		for i, attachment := range attachments {
+			names[i] = attachment.Name
+			urls[i] = attachment.dataURL()
+		}
+
+		output["attachment_name"] = names
+		output["attachment"] = urls
 	}
 
 	if len(p.Data) > 0 {
@@ -66,48 +99,151 @@ func (p Paste) MarshalJSON() ([]byte, error) {
 }
 
 func (p *Paste) UnmarshalJSON(data []byte) error {
-	output := map[string]string{}
+	output := map[string]json.RawMessage{}
 	err := json.Unmarshal(data, &output)
 	if err != nil {
 		return err
 	}
 
-	var attachment []byte
-	var mimeType string
-	attachmentURL, ok := output["attachment"]
-	if ok {
-		parsedURL, err := url.Parse(attachmentURL)
-		if err != nil {
-			return fmt.Errorf("invalid attachment: error parsing url: %w", err)
+	var text string
+	if raw, ok := output["paste"]; ok {
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return fmt.Errorf("invalid paste: %w", err)
 		}
+	}
 
-		parts := strings.Split(parsedURL.Opaque, ",")
-		if len(parts) != 2 {
-			return errors.New("invalid attachment: invalid data URL format")
-		}
+	urls, err := unmarshalStringOrStrings(output["attachment"])
+	if err != nil {
+		return fmt.Errorf("invalid attachment: %w", err)
+	}
 
-		if !strings.HasSuffix(parts[0], ";base64") {
-			return errors.New("invalid attachment: missing or invalid base64 encoding")
-		}
+	names, err := unmarshalStringOrStrings(output["attachment_name"])
+	if err != nil {
+		return fmt.Errorf("invalid attachment name: %w", err)
+	}
 
-		mimeType = strings.TrimSuffix(parts[0], ";base64")
-		if mimeType == "" {
-			mimeType = "application/octet-stream"
+	var attachments []Attachment
+	for i, attachmentURL := range urls {
+		attachment, err := parseDataURL(attachmentURL)
+		if err != nil {
+			return err
 		}
 
-		attachment, err = decode64(parts[1])
-		if err != nil {
-			return fmt.Errorf("invalid attachment: cannot base64 decode data: %w", err)
+		if i < len(names) {
+			attachment.Name = names[i]
 		}
 
+		attachments = append(attachments, attachment)
 	}
 
 	*p = Paste{
-		[]byte(output["paste"]),
-		attachment,
-		output["attachment_name"],
-		mimeType,
+		Data:        []byte(text),
+		Attachments: attachments,
+	}
+
+	if len(attachments) > 0 {
+		p.Attachment = attachments[0].Data
+		p.AttachmentName = attachments[0].Name
+		p.MimeType = attachments[0].MimeType
 	}
 
 	return nil
 }
+
+func (a Attachment) dataURL() string {
+	return fmt.Sprintf(
+		"data:%s;base64,%s",
+		a.mimeType(),
+		base64.StdEncoding.EncodeToString(a.Data),
+	)
+}
+
+func (a Attachment) mimeType() string {
+	if a.MimeType != "" {
+		return a.MimeType
+	}
+
+	if mimeType := mime.TypeByExtension(filepath.Ext(a.Name)); mimeType != "" {
+		return mimeType
+	}
+
+	return "application/octet-stream"
+}
+
+// SafeName returns the attachment name reduced to a single path element
+// that can be used as a file name: directories, control and formatting
+// characters (such as bidirectional overrides) and leading or trailing
+// spaces are removed. It returns an empty string when
+// nothing usable remains, as the name comes from the paste author.
+func (a Attachment) SafeName() string {
+	name := strings.Map(
+		func(r rune) rune {
+			if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+				return -1
+			}
+			return r
+		},
+		a.Name,
+	)
+
+	// Both separators are handled whatever the platform, a name written
+	// on Windows must not escape the directory on Unix and vice versa.
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "." || name == ".." {
+		return ""
+	}
+
+	return name
+}
+
+func parseDataURL(attachmentURL string) (Attachment, error) {
+	parsedURL, err := url.Parse(attachmentURL)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("invalid attachment: error parsing url: %w", err)
+	}
+
+	parts := strings.Split(parsedURL.Opaque, ",")
+	if len(parts) != 2 {
+		return Attachment{}, errors.New("invalid attachment: invalid data URL format")
+	}
+
+	if !strings.HasSuffix(parts[0], ";base64") {
+		return Attachment{}, errors.New("invalid attachment: missing or invalid base64 encoding")
+	}
+
+	mimeType := strings.TrimSuffix(parts[0], ";base64")
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	data, err := decode64(parts[1])
+	if err != nil {
+		return Attachment{}, fmt.Errorf("invalid attachment: cannot base64 decode data: %w", err)
+	}
+
+	return Attachment{MimeType: mimeType, Data: data}, nil
+}
+
+// unmarshalStringOrStrings decodes a JSON string or array of strings, as
+// PrivateBin uses both forms for the attachment fields.
+func unmarshalStringOrStrings(data json.RawMessage) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return []string{s}, nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, errors.New("expected a string or an array of strings")
+	}
+
+	return values, nil
+}
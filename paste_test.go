@@ -506,4 +506,239 @@ func TestPaste_UTF8Bug(t *testing.T) {
 	expected := []byte{239, 191, 189} // UTF-8 replacement character
 	assert.Equal(t, expected, paste2.Data, "Binary data should be corrupted due to bug")
 	assert.NotEqual(t, paste.Data, paste2.Data, "Original binary data should not match round-trip")
-}
\ No newline at end of file
+}
+
+func TestPaste_MultipleAttachments(t *testing.T) {
+	paste := Paste{
+		Data: []byte("two files"),
+		Attachments: []Attachment{
+			{Name: "a.txt", Data: []byte("first")},
+			{Name: "b.png", MimeType: "image/png", Data: []byte("second")},
+		},
+	}
+
+	data, err := json.Marshal(paste)
+	require.NoError(t, err)
+
+	var result struct {
+		Paste          string   `json:"paste"`
+		Attachment     []string `json:"attachment"`
+		AttachmentName []string `json:"attachment_name"`
+	}
+	require.NoError(t, json.Unmarshal(data, &result))
+	assert.Equal(t, "two files", result.Paste)
+	assert.Equal(t, []string{"a.txt", "b.png"}, result.AttachmentName)
+	assert.Equal(
+		t,
+		[]string{
+			"data:text/plain; charset=utf-8;base64," + base64.StdEncoding.EncodeToString([]byte("first")),
+			"data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("second")),
+		},
+		result.Attachment,
+	)
+
+	var paste2 Paste
+	require.NoError(t, json.Unmarshal(data, &paste2))
+	assert.Equal(t, paste.Data, paste2.Data)
+	require.Len(t, paste2.Attachments, 2)
+	assert.Equal(t, "a.txt", paste2.Attachments[0].Name)
+	assert.Equal(t, []byte("first"), paste2.Attachments[0].Data)
+	assert.Equal(t, "b.png", paste2.Attachments[1].Name)
+	assert.Equal(t, "image/png", paste2.Attachments[1].MimeType)
+	assert.Equal(t, []byte("second"), paste2.Attachments[1].Data)
+}
+
+func TestPaste_MarshalJSON_Attachments(t *testing.T) {
+	tests := []struct {
+		name  string
+		paste Paste
+	}{
+		{
+			name: "One attachment with explicit MIME type",
+			paste: Paste{
+				Data:        []byte("Some text"),
+				Attachments: []Attachment{{Name: "test.txt", MimeType: "text/plain", Data: []byte("file content")}},
+			},
+		},
+		{
+			name: "One attachment, name inferred MIME type",
+			paste: Paste{
+				Attachments: []Attachment{{Name: "image.png", Data: []byte{137, 80, 78, 71}}},
+			},
+		},
+		{
+			name: "Several attachments",
+			paste: Paste{
+				Data: []byte("Some text"),
+				Attachments: []Attachment{
+					{Name: "a.txt", Data: []byte("first")},
+					{Data: []byte{0x00, 0x01}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.paste)
+			require.NoError(t, err)
+
+			var paste Paste
+			require.NoError(t, json.Unmarshal(data, &paste))
+
+			assert.True(t, bytesEqual(tt.paste.Data, paste.Data), "Data should match")
+			require.Len(t, paste.Attachments, len(tt.paste.Attachments))
+			for i, attachment := range tt.paste.Attachments {
+				assert.True(t, bytesEqual(attachment.Data, paste.Attachments[i].Data), "Attachment should match")
+				assert.Equal(t, attachment.Name, paste.Attachments[i].Name)
+				assert.NotEmpty(t, paste.Attachments[i].MimeType, "Should have a MIME type")
+			}
+		})
+	}
+}
+
+func TestPaste_DeprecatedAttachmentFields(t *testing.T) {
+	t.Run("Encoded when Attachments is empty", func(t *testing.T) {
+		paste := Paste{
+			Data:           []byte("text"),
+			Attachment:     []byte("content"),
+			AttachmentName: "notes.txt",
+			MimeType:       "text/plain",
+		}
+
+		data, err := json.Marshal(paste)
+		require.NoError(t, err)
+
+		var decoded Paste
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Len(t, decoded.Attachments, 1)
+		assert.Equal(t, Attachment{Name: "notes.txt", MimeType: "text/plain", Data: []byte("content")}, decoded.Attachments[0])
+	})
+
+	t.Run("Ignored when Attachments is set", func(t *testing.T) {
+		paste := Paste{
+			Attachment:     []byte("legacy"),
+			AttachmentName: "legacy.txt",
+			Attachments:    []Attachment{{Name: "a.txt", Data: []byte("a")}},
+		}
+
+		data, err := json.Marshal(paste)
+		require.NoError(t, err)
+
+		var decoded Paste
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		require.Len(t, decoded.Attachments, 1)
+		assert.Equal(t, "a.txt", decoded.Attachments[0].Name)
+	})
+
+	t.Run("Decoded from the first attachment", func(t *testing.T) {
+		paste := Paste{
+			Attachments: []Attachment{
+				{Name: "a.txt", MimeType: "text/plain", Data: []byte("a")},
+				{Name: "b.txt", MimeType: "text/plain", Data: []byte("b")},
+			},
+		}
+
+		data, err := json.Marshal(paste)
+		require.NoError(t, err)
+
+		var decoded Paste
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, []byte("a"), decoded.Attachment)
+		assert.Equal(t, "a.txt", decoded.AttachmentName)
+		assert.Equal(t, "text/plain", decoded.MimeType)
+	})
+}
+
+func TestPaste_UnmarshalJSON_AttachmentArrays(t *testing.T) {
+	first := base64.StdEncoding.EncodeToString([]byte("first"))
+	second := base64.StdEncoding.EncodeToString([]byte("second"))
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []Attachment
+		wantErr string
+	}{
+		{
+			name: "arrays",
+			input: fmt.Sprintf(
+				`{"attachment":["data:text/plain;base64,%s","data:;base64,%s"],"attachment_name":["a.txt","b"]}`,
+				first,
+				second,
+			),
+			want: []Attachment{
+				{Name: "a.txt", MimeType: "text/plain", Data: []byte("first")},
+				{Name: "b", MimeType: "application/octet-stream", Data: []byte("second")},
+			},
+		},
+		{
+			name: "fewer names than attachments",
+			input: fmt.Sprintf(
+				`{"attachment":["data:text/plain;base64,%s","data:text/plain;base64,%s"],"attachment_name":["a.txt"]}`,
+				first,
+				second,
+			),
+			want: []Attachment{
+				{Name: "a.txt", MimeType: "text/plain", Data: []byte("first")},
+				{MimeType: "text/plain", Data: []byte("second")},
+			},
+		},
+		{
+			name:  "empty array",
+			input: `{"attachment":[],"attachment_name":[]}`,
+		},
+		{
+			name:    "invalid attachment type",
+			input:   `{"attachment":42}`,
+			wantErr: "invalid attachment: expected a string or an array of strings",
+		},
+		{
+			name:    "invalid data url in array",
+			input:   `{"attachment":["data:text/plain,raw"]}`,
+			wantErr: "missing or invalid base64 encoding",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paste Paste
+			err := json.Unmarshal([]byte(tt.input), &paste)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, paste.Attachments)
+		})
+	}
+}
+
+func TestAttachment_SafeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{".bashrc", ".bashrc"},
+		{"../../etc/passwd", "passwd"},
+		{"/etc/passwd", "passwd"},
+		{`C:\Windows\system.ini`, "system.ini"},
+		{`..\..\boot.ini`, "boot.ini"},
+		{"evil\x1b[31mname\n.txt", "evil[31mname.txt"},
+		{"invoice\u202efdp.exe", "invoicefdp.exe"},
+		{"  spaced  ", "spaced"},
+		{"dir/", ""},
+		{"..", ""},
+		{".", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Attachment{Name: tt.name}.SafeName())
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	pasteIDSize = 8
+)
+
+type (
+	// PasteRef identifies a paste on a PrivateBin instance, it is the
+	// parsed form of a paste URL.
+	PasteRef struct {
+		// Instance is the URL of the PrivateBin instance, without query
+		// nor fragment.
+		Instance url.URL
+
+		PasteID string
+
+		// MasterKey is nil when the URL does not carry the key.
+		MasterKey MasterKey
+
+		BurnAfterReading bool
+	}
+)
+
+// ParsePasteURL parses a paste URL. The paste ID is read from the bare
+// query string ("?f468483c313401e8"), the "pasteid" query parameter or,
+// for instances with rewritten URLs, the last path element. The fragment
+// holds the master key, prefixed with "-" for burn after reading pastes.
+func ParsePasteURL(rawURL string) (PasteRef, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		// Drop the quoted URL, it may carry the key.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return PasteRef{}, fmt.Errorf("cannot parse paste url: %w", err)
+	}
+
+	return parsePasteRef(*u)
+}
+
+func parsePasteRef(u url.URL) (PasteRef, error) {
+	if u.Scheme == "" || u.Host == "" {
+		u.Fragment = ""
+		return PasteRef{}, fmt.Errorf("cannot parse paste url %q: missing scheme or host", u.Redacted())
+	}
+
+	pasteID, err := pasteIDFromURL(u)
+	if err != nil {
+		return PasteRef{}, err
+	}
+
+	instance := url.URL{
+		Scheme: u.Scheme,
+		User:   u.User,
+		Host:   u.Host,
+		Path:   u.Path,
+	}
+
+	if u.RawQuery == "" {
+		// The paste ID is the last path element, a trailing slash
+		// included.
+		instance.Path = path.Dir(strings.TrimRight(u.Path, "/"))
+		if !strings.HasSuffix(instance.Path, "/") {
+			instance.Path += "/"
+		}
+	}
+
+	ref := PasteRef{
+		Instance: instance,
+		PasteID:  pasteID,
+	}
+
+	fragment := u.Fragment
+	if strings.HasPrefix(fragment, "-") {
+		fragment = fragment[1:]
+		ref.BurnAfterReading = true
+	}
+
+	if fragment != "" {
+		ref.MasterKey, err = ParseMasterKey(fragment)
+		if err != nil {
+			return PasteRef{}, err
+		}
+	}
+
+	return ref, nil
+}
+
+// URL returns the paste URL, with the paste ID as bare query string.
+func (r PasteRef) URL() url.URL {
+	u := r.Instance
+	u.RawQuery = r.PasteID
+
+	if r.MasterKey != nil {
+		u.Fragment = r.MasterKey.String()
+		if r.BurnAfterReading {
+			u.Fragment = "-" + u.Fragment
+		}
+	}
+
+	return u
+}
+
+func (r PasteRef) String() string {
+	u := r.URL()
+	return u.String()
+}
+
+// pasteIDFromURL extracts the paste identifier from a paste URL. PrivateBin
+// links carry it either as the bare query string ("?f468483c313401e8"), as
+// the "pasteid" query parameter or, when the instance rewrites URLs, as the
+// last path element.
+func pasteIDFromURL(u url.URL) (string, error) {
+	u.Fragment = ""
+
+	var id string
+	switch {
+	case u.Query().Get("pasteid") != "":
+		id = u.Query().Get("pasteid")
+	case u.RawQuery != "" && !strings.ContainsAny(u.RawQuery, "=&"):
+		id = u.RawQuery
+	case u.RawQuery == "" && isPasteID(path.Base(u.Path)):
+		id = path.Base(u.Path)
+	default:
+		return "", fmt.Errorf("cannot find paste id in %q", u.Redacted())
+	}
+
+	if !isPasteID(id) {
+		return "", fmt.Errorf("invalid paste id %q in %q", id, u.Redacted())
+	}
+
+	return id, nil
+}
+
+// isPasteID reports whether id is made of 16 hexadecimal characters, the
+// format of the identifiers generated by PrivateBin.
+func isPasteID(id string) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == pasteIDSize
+}
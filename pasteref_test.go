@@ -0,0 +1,129 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.gearno.de/encoding/base58"
+)
+
+func TestParsePasteURL(t *testing.T) {
+	masterKey := MasterKey("0123456789abcdef0123456789abcdef")
+	key := base58.Encode(masterKey)
+
+	tests := []struct {
+		name      string
+		input     string
+		instance  string
+		pasteID   string
+		masterKey MasterKey
+		burn      bool
+		canonical string
+		wantErr   bool
+	}{
+		{
+			name:      "Bare query string",
+			input:     "https://privatebin.net/?f468483c313401e8#" + key,
+			instance:  "https://privatebin.net/",
+			pasteID:   "f468483c313401e8",
+			masterKey: masterKey,
+			canonical: "https://privatebin.net/?f468483c313401e8#" + key,
+		},
+		{
+			name:      "Pasteid query parameter",
+			input:     "https://privatebin.net/bin/?pasteid=f468483c313401e8#-" + key,
+			instance:  "https://privatebin.net/bin/",
+			pasteID:   "f468483c313401e8",
+			masterKey: masterKey,
+			burn:      true,
+			canonical: "https://privatebin.net/bin/?f468483c313401e8#-" + key,
+		},
+		{
+			name:      "Rewritten url",
+			input:     "https://privatebin.net/bin/f468483c313401e8#" + key,
+			instance:  "https://privatebin.net/bin/",
+			pasteID:   "f468483c313401e8",
+			masterKey: masterKey,
+			canonical: "https://privatebin.net/bin/?f468483c313401e8#" + key,
+		},
+		{
+			name:      "Rewritten url with trailing slash",
+			input:     "https://privatebin.net/bin/f468483c313401e8/#" + key,
+			instance:  "https://privatebin.net/bin/",
+			pasteID:   "f468483c313401e8",
+			masterKey: masterKey,
+			canonical: "https://privatebin.net/bin/?f468483c313401e8#" + key,
+		},
+		{
+			name:      "Rewritten url at the root with trailing slash",
+			input:     "https://privatebin.net/f468483c313401e8/",
+			instance:  "https://privatebin.net/",
+			pasteID:   "f468483c313401e8",
+			canonical: "https://privatebin.net/?f468483c313401e8",
+		},
+		{
+			name:      "Without master key",
+			input:     "https://privatebin.net/?f468483c313401e8",
+			instance:  "https://privatebin.net/",
+			pasteID:   "f468483c313401e8",
+			canonical: "https://privatebin.net/?f468483c313401e8",
+		},
+		{
+			name:    "Short master key",
+			input:   "https://privatebin.net/?f468483c313401e8#" + base58.Encode([]byte("short")),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid paste id",
+			input:   "https://privatebin.net/?pasteid=not-an-id#" + key,
+			wantErr: true,
+		},
+		{
+			name:    "No paste id",
+			input:   "https://privatebin.net/#" + key,
+			wantErr: true,
+		},
+		{
+			name:    "Relative url",
+			input:   "?f468483c313401e8#" + key,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := ParsePasteURL(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.NotContains(t, err.Error(), key)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.instance, ref.Instance.String())
+			assert.Equal(t, tt.pasteID, ref.PasteID)
+			assert.Equal(t, tt.masterKey, ref.MasterKey)
+			assert.Equal(t, tt.burn, ref.BurnAfterReading)
+			assert.Equal(t, tt.canonical, ref.String())
+
+			parsed, err := ParsePasteURL(ref.String())
+			require.NoError(t, err)
+			assert.Equal(t, ref, parsed)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+const (
+	// maxResponseOverhead is the room left in a response for the JSON
+	// fields around the paste cipher text, and for the comments.
+	maxResponseOverhead = 1 << 20
+)
+
+var (
+	// ErrPolicyViolation is returned when the encryption parameters or the
+	// size of the data sent by the server are rejected by the
+	// DecryptPolicy. Use errors.As with *PolicyViolationError to know
+	// which parameter was rejected.
+	ErrPolicyViolation = errors.New("decrypt policy violation")
+)
+
+type (
+	// DecryptPolicy bounds the parameters an untrusted server can make the
+	// client use when decrypting a paste or a comment. A zero field
+	// disables the corresponding check.
+	DecryptPolicy struct {
+		// MinIterations and MaxIterations bound the number of PBKDF2
+		// iterations, too few weaken the password and too many burn CPU.
+		MinIterations int
+		MaxIterations int
+
+		// AllowedKeySizes and AllowedTagSizes list the accepted key and
+		// authentication tag sizes, in bits.
+		AllowedKeySizes []int
+		AllowedTagSizes []int
+
+		// MaxCipherTextSize is the maximum size in bytes of the decoded
+		// cipher text. It also bounds the size of the responses read
+		// from the server, to its base64 encoding plus
+		// maxResponseOverhead for the JSON fields and the comments.
+		MaxCipherTextSize int64
+
+		// MaxDecompressedSize is the maximum size in bytes of the
+		// plaintext once inflated.
+		MaxDecompressedSize int64
+
+		// MaxCommentIterations bounds the sum of the PBKDF2 iterations
+		// of the comments of a paste, checked before any of them is
+		// decrypted, so that many comments cannot burn CPU either.
+		MaxCommentIterations int64
+	}
+
+	PolicyViolationError struct {
+		Parameter string
+		Value     int64
+	}
+)
+
+// DefaultDecryptPolicy returns the policy used by clients created without
+// WithDecryptPolicy. It accepts the parameters used by PrivateBin and this
+// package, pastes up to 64 MiB of cipher text inflating to 128 MiB, and
+// about 200 comments encrypted with the PrivateBin default iterations.
+func DefaultDecryptPolicy() DecryptPolicy {
+	return DecryptPolicy{
+		MinIterations:        100_000,
+		MaxIterations:        10_000_000,
+		AllowedKeySizes:      []int{128, 192, 256},
+		AllowedTagSizes:      []int{96, 104, 112, 120, 128},
+		MaxCipherTextSize:    64 << 20,
+		MaxDecompressedSize:  128 << 20,
+		MaxCommentIterations: 120_000_000,
+	}
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("decrypt policy violation: %s %d is not allowed", e.Parameter, e.Value)
+}
+
+func (e *PolicyViolationError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// checkSpec verifies the encryption parameters before any key derivation.
+func (p DecryptPolicy) checkSpec(spec Spec) error {
+	if p.MinIterations > 0 && spec.Iterations < p.MinIterations {
+		return &PolicyViolationError{"iterations", int64(spec.Iterations)}
+	}
+
+	if p.MaxIterations > 0 && spec.Iterations > p.MaxIterations {
+		return &PolicyViolationError{"iterations", int64(spec.Iterations)}
+	}
+
+	if len(p.AllowedKeySizes) > 0 && !slices.Contains(p.AllowedKeySizes, spec.KeySize) {
+		return &PolicyViolationError{"key size", int64(spec.KeySize)}
+	}
+
+	if len(p.AllowedTagSizes) > 0 && !slices.Contains(p.AllowedTagSizes, spec.TagSize) {
+		return &PolicyViolationError{"tag size", int64(spec.TagSize)}
+	}
+
+	return nil
+}
+
+// checkCommentIterations verifies the total key derivation cost of the
+// comments before any of them is decrypted.
+func (p DecryptPolicy) checkCommentIterations(comments []showPasteResponseComment) error {
+	if p.MaxCommentIterations <= 0 {
+		return nil
+	}
+
+	var total int64
+	for _, comment := range comments {
+		iterations := max(int64(comment.AData.Iterations), 0)
+		if iterations > p.MaxCommentIterations-total {
+			value := total + iterations
+			if value < 0 {
+				value = math.MaxInt64
+			}
+
+			return &PolicyViolationError{"comment iterations", value}
+		}
+
+		total += iterations
+	}
+
+	return nil
+}
+
+// maxResponseSize returns the maximum size in bytes of a response body, or
+// zero when the cipher text size is not bounded.
+func (p DecryptPolicy) maxResponseSize() int64 {
+	if p.MaxCipherTextSize <= 0 {
+		return 0
+	}
+
+	// Padded base64 encodes each 3 bytes group in 4 bytes.
+	return (p.MaxCipherTextSize+2)/3*4 + maxResponseOverhead
+}
+
+func (p DecryptPolicy) checkCipherTextSize(n int64) error {
+	if p.MaxCipherTextSize > 0 && n > p.MaxCipherTextSize {
+		return &PolicyViolationError{"cipher text size", n}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"bytes"
+	"compress/flate"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecryptPolicy_CheckSpec(t *testing.T) {
+	policy := DefaultDecryptPolicy()
+	valid := Spec{Iterations: 600000, KeySize: 256, TagSize: 128}
+
+	tests := []struct {
+		name      string
+		spec      func(Spec) Spec
+		parameter string
+	}{
+		{
+			name: "valid spec",
+			spec: func(s Spec) Spec { return s },
+		},
+		{
+			name:      "too few iterations",
+			spec:      func(s Spec) Spec { s.Iterations = 1000; return s },
+			parameter: "iterations",
+		},
+		{
+			name:      "too many iterations",
+			spec:      func(s Spec) Spec { s.Iterations = 1 << 30; return s },
+			parameter: "iterations",
+		},
+		{
+			name:      "key size not allowed",
+			spec:      func(s Spec) Spec { s.KeySize = 64; return s },
+			parameter: "key size",
+		},
+		{
+			name:      "tag size not allowed",
+			spec:      func(s Spec) Spec { s.TagSize = 32; return s },
+			parameter: "tag size",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.checkSpec(tt.spec(valid))
+			if tt.parameter == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *PolicyViolationError
+			require.ErrorAs(t, err, &policyErr)
+			assert.Equal(t, tt.parameter, policyErr.Parameter)
+			assert.ErrorIs(t, err, ErrPolicyViolation)
+		})
+	}
+
+	t.Run("zero policy allows everything", func(t *testing.T) {
+		err := DecryptPolicy{}.checkSpec(Spec{Iterations: 1, KeySize: 8, TagSize: 8})
+		assert.NoError(t, err)
+	})
+}
+
+func TestDecrypt_Policy(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	adata := []byte("adata")
+
+	spec, err := newSpec(CompressionAlgorithmGZip)
+	require.NoError(t, err)
+
+	data := bytes.Repeat([]byte("a"), 1<<20)
+	ct, err := encrypt(key, data, adata, spec, discardLogger)
+	require.NoError(t, err)
+
+	t.Run("within limits", func(t *testing.T) {
+		out, err := decrypt(key, ct, adata, spec, DefaultDecryptPolicy(), discardLogger)
+		require.NoError(t, err)
+		assert.Equal(t, data, out)
+	})
+
+	t.Run("decompressed size exceeded", func(t *testing.T) {
+		policy := DefaultDecryptPolicy()
+		policy.MaxDecompressedSize = 1024
+
+		_, err := decrypt(key, ct, adata, spec, policy, discardLogger)
+		var policyErr *PolicyViolationError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, "decompressed size", policyErr.Parameter)
+	})
+
+	t.Run("cipher text size exceeded", func(t *testing.T) {
+		policy := DefaultDecryptPolicy()
+		policy.MaxCipherTextSize = 16
+
+		_, err := decrypt(key, ct, adata, spec, policy, discardLogger)
+		var policyErr *PolicyViolationError
+		require.ErrorAs(t, err, &policyErr)
+		assert.Equal(t, "cipher text size", policyErr.Parameter)
+	})
+
+	t.Run("rejected before key derivation", func(t *testing.T) {
+		hostile := spec
+		hostile.Iterations = 1 << 30
+
+		_, err := decrypt(key, ct, adata, hostile, DefaultDecryptPolicy(), discardLogger)
+		assert.ErrorIs(t, err, ErrPolicyViolation)
+	})
+}
+
+func TestDecrypt_DecompressionBomb(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	adata := []byte("adata")
+
+	spec, err := newSpec(CompressionAlgorithmGZip)
+	require.NoError(t, err)
+
+	// Build the deflate stream directly so the bomb is cheap to produce.
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestCompression)
+	require.NoError(t, err)
+	zeros := make([]byte, 1<<20)
+	for range 64 {
+		_, err = fw.Write(zeros)
+		require.NoError(t, err)
+	}
+	require.NoError(t, fw.Close())
+
+	raw := spec
+	raw.Compression = CompressionAlgorithmNone
+	ct, err := encrypt(key, buf.Bytes(), adata, raw, discardLogger)
+	require.NoError(t, err)
+
+	policy := DefaultDecryptPolicy()
+	policy.MaxDecompressedSize = 1 << 20
+
+	_, err = decrypt(key, ct, adata, spec, policy, discardLogger)
+	assert.ErrorIs(t, err, ErrPolicyViolation)
+}
+
+func TestDecryptPolicy_CheckCommentIterations(t *testing.T) {
+	comment := func(iterations int) showPasteResponseComment {
+		return showPasteResponseComment{AData: Spec{Iterations: iterations}}
+	}
+
+	policy := DecryptPolicy{MaxCommentIterations: 1_000_000}
+
+	tests := []struct {
+		name      string
+		comments  []showPasteResponseComment
+		wantValue int64
+	}{
+		{
+			name:     "within the budget",
+			comments: []showPasteResponseComment{comment(600_000), comment(400_000)},
+		},
+		{
+			name:      "above the budget",
+			comments:  []showPasteResponseComment{comment(600_000), comment(600_000), comment(600_000)},
+			wantValue: 1_200_000,
+		},
+		{
+			name:      "overflowing iterations",
+			comments:  []showPasteResponseComment{comment(600_000), comment(math.MaxInt64)},
+			wantValue: math.MaxInt64,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.checkCommentIterations(tt.comments)
+			if tt.wantValue == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *PolicyViolationError
+			require.ErrorAs(t, err, &policyErr)
+			assert.Equal(t, "comment iterations", policyErr.Parameter)
+			assert.Equal(t, tt.wantValue, policyErr.Value)
+		})
+	}
+
+	t.Run("zero policy allows everything", func(t *testing.T) {
+		err := DecryptPolicy{}.checkCommentIterations([]showPasteResponseComment{comment(math.MaxInt64), comment(math.MaxInt64)})
+		assert.NoError(t, err)
+	})
+}
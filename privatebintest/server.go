@@ -0,0 +1,201 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+// Package privatebintest provides an in-memory PrivateBin server for tests.
+//
+// The server speaks the PrivateBin v2 JSON API over an httptest.Server so
+// that code built on top of privatebin.Client can be exercised without a
+// real PHP instance. It is backed by the server package with a memory
+// storage, and can be switched to misbehave like the PrivateBin instances
+// found in the wild.
+package privatebintest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"go.gearno.de/privatebin/v2/server"
+)
+
+const (
+	// FailureNone serves requests normally.
+	FailureNone FailureMode = iota
+
+	// FailureInternalServerError answers every request with a 500 status
+	// code and a plain text body.
+	FailureInternalServerError
+
+	// FailureEmptyMeta encodes the paste meta field as an empty JSON array,
+	// the way PHP serializes an empty associative array.
+	FailureEmptyMeta
+
+	// FailureHTMLError answers every request with an HTML error page, like
+	// a misconfigured reverse proxy would.
+	FailureHTMLError
+
+	// FailureRateLimited rejects every post with the message of the
+	// PrivateBin traffic limiter.
+	FailureRateLimited
+)
+
+type (
+	// FailureMode selects how the server misbehaves.
+	FailureMode int
+
+	// Server is a fake PrivateBin instance backed by memory.
+	Server struct {
+		*httptest.Server
+
+		storage *server.MemoryStorage
+		handler *server.Server
+
+		mu               sync.Mutex
+		now              func() time.Time
+		failureMode      FailureMode
+		rateLimitSeconds int
+	}
+
+	Option func(s *Server)
+)
+
+// WithClock replaces the clock used to timestamp pastes and decide
+// whether they have expired.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithRateLimitSeconds sets the number of seconds announced by the traffic
+// limiter message when FailureRateLimited is active. It defaults to 10.
+func WithRateLimitSeconds(seconds int) Option {
+	return func(s *Server) {
+		s.rateLimitSeconds = seconds
+	}
+}
+
+// NewServer starts a fake PrivateBin server. The caller must call Close
+// when done.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		storage:          server.NewMemoryStorage(),
+		now:              time.Now,
+		rateLimitSeconds: 10,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	s.handler = server.New(
+		s.storage,
+		server.WithClock(func() time.Time { return s.now() }),
+	)
+	s.Server = httptest.NewServer(s)
+
+	return s
+}
+
+// Endpoint returns the URL of the instance, suitable for
+// privatebin.NewClient.
+func (s *Server) Endpoint() url.URL {
+	u, _ := url.Parse(s.URL + "/")
+	return *u
+}
+
+// SetFailureMode changes how the server answers subsequent requests.
+func (s *Server) SetFailureMode(mode FailureMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failureMode = mode
+}
+
+// HasPaste reports whether the paste is still stored and not expired.
+func (s *Server) HasPaste(id string) bool {
+	_, err := s.handler.ReadPaste(context.Background(), id)
+	return err == nil
+}
+
+// DeleteToken returns the delete token the server issued for the paste, or
+// an empty string when the paste does not exist.
+func (s *Server) DeleteToken(pasteID string) string {
+	paste, err := s.storage.ReadPaste(context.Background(), pasteID)
+	if err != nil {
+		return ""
+	}
+
+	return server.DeleteToken(paste)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	mode := s.failureMode
+	s.mu.Unlock()
+
+	switch mode {
+	case FailureInternalServerError:
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	case FailureHTMLError:
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		_, _ = io.WriteString(w, "<!DOCTYPE html><html><head><title>502 Bad Gateway</title></head><body><h1>Bad Gateway</h1></body></html>\n")
+	case FailureRateLimited:
+		if r.Method != http.MethodPost {
+			s.handler.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(
+			map[string]any{
+				"status":  1,
+				"message": fmt.Sprintf("Please wait %d seconds between each post.", s.rateLimitSeconds),
+			},
+		)
+	case FailureEmptyMeta:
+		s.serveEmptyMeta(w, r)
+	default:
+		s.handler.ServeHTTP(w, r)
+	}
+}
+
+func (s *Server) serveEmptyMeta(w http.ResponseWriter, r *http.Request) {
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, r)
+
+	body := rec.Body.Bytes()
+
+	var res map[string]json.RawMessage
+	if r.Method == http.MethodGet && json.Unmarshal(body, &res) == nil {
+		if _, ok := res["meta"]; ok {
+			res["meta"] = json.RawMessage("[]")
+			body, _ = json.Marshal(res)
+		}
+	}
+
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+
+	w.WriteHeader(rec.Code)
+	_, _ = io.Copy(w, bytes.NewReader(body))
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebintest
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.gearno.de/privatebin/v2"
+)
+
+func TestServer_CreateAndShowPaste(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := NewServer(WithClock(func() time.Time { return now }))
+	defer server.Close()
+
+	client := privatebin.NewClient(server.Endpoint())
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(
+		ctx,
+		[]byte("hello world"),
+		privatebin.CreatePasteOptions{
+			Formatter:      privatebin.FormatterMarkdown,
+			Expire:         privatebin.Expire1Day,
+			OpenDiscussion: true,
+			Compress:       privatebin.CompressionAlgorithmGZip,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, server.DeleteToken(created.PasteID), created.DeleteToken)
+
+	_, err = client.CreateComment(ctx, created.PasteURL, "", "alice", "first", privatebin.CreateCommentOptions{})
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+
+	shown, err := client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, created.PasteID, shown.PasteID)
+	assert.True(t, shown.Created.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 23*time.Hour, shown.TimeToLive)
+	assert.Equal(t, privatebin.FormatterMarkdown, shown.Formatter)
+	assert.True(t, shown.OpenDiscussion)
+	assert.False(t, shown.BurnAfterReading)
+	assert.Equal(t, "hello world", string(shown.Paste.Data))
+	require.Len(t, shown.Comments, 1)
+	assert.Equal(t, "alice", shown.Comments[0].Nickname)
+	assert.Equal(t, "first", shown.Comments[0].Text)
+	assert.Equal(t, created.PasteID, shown.Comments[0].ParentID)
+
+	shown, err = client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{SkipComments: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, shown.CommentCount)
+	assert.Empty(t, shown.Comments)
+}
+
+func TestServer_BurnAfterReading(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client := privatebin.NewClient(server.Endpoint())
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(
+		ctx,
+		[]byte("secret"),
+		privatebin.CreatePasteOptions{
+			BurnAfterReading: true,
+			Compress:         privatebin.CompressionAlgorithmNone,
+		},
+	)
+	require.NoError(t, err)
+
+	opts := privatebin.ShowPasteOptions{ConfirmBurn: true}
+
+	_, err = client.ShowPaste(ctx, created.PasteURL, opts)
+	require.NoError(t, err)
+	assert.False(t, server.HasPaste(created.PasteID))
+
+	_, err = client.ShowPaste(ctx, created.PasteURL, opts)
+	require.ErrorIs(t, err, privatebin.ErrPasteNotFound)
+
+	_, err = client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{})
+	require.ErrorIs(t, err, privatebin.ErrBurnNotConfirmed)
+}
+
+func TestServer_WrongPassword(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client := privatebin.NewClient(server.Endpoint())
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(
+		ctx,
+		[]byte("secret"),
+		privatebin.CreatePasteOptions{Password: []byte("right")},
+	)
+	require.NoError(t, err)
+
+	_, err = client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{Password: []byte("wrong")})
+	require.ErrorIs(t, err, privatebin.ErrWrongPassword)
+
+	shown, err := client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{Password: []byte("right")})
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(shown.Paste.Data))
+}
+
+func TestServer_Expire(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := NewServer(WithClock(func() time.Time { return now }))
+	defer server.Close()
+
+	client := privatebin.NewClient(server.Endpoint())
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(
+		ctx,
+		[]byte("ephemeral"),
+		privatebin.CreatePasteOptions{
+			Expire:   privatebin.Expire5Min,
+			Compress: privatebin.CompressionAlgorithmNone,
+		},
+	)
+	require.NoError(t, err)
+
+	now = now.Add(4 * time.Minute)
+	assert.True(t, server.HasPaste(created.PasteID))
+
+	now = now.Add(time.Minute)
+	assert.False(t, server.HasPaste(created.PasteID))
+}
+
+func TestServer_DeletePaste(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	client := privatebin.NewClient(server.Endpoint())
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(
+		ctx,
+		[]byte("to delete"),
+		privatebin.CreatePasteOptions{Compress: privatebin.CompressionAlgorithmNone},
+	)
+	require.NoError(t, err)
+
+	err = client.DeletePaste(ctx, created.PasteID, "bad")
+	require.ErrorIs(t, err, privatebin.ErrInvalidDeleteToken)
+
+	err = client.DeletePaste(ctx, created.PasteURL.String(), created.DeleteToken)
+	require.NoError(t, err)
+
+	err = client.DeletePaste(ctx, created.PasteID, created.DeleteToken)
+	require.ErrorIs(t, err, privatebin.ErrPasteNotFound)
+}
+
+func TestServer_FailureModes(t *testing.T) {
+	tests := []struct {
+		name           string
+		mode           FailureMode
+		wantCreate     bool
+		wantShow       bool
+		wantHTTPStatus int
+	}{
+		{name: "Internal server error", mode: FailureInternalServerError, wantHTTPStatus: 500},
+		{name: "HTML error page", mode: FailureHTMLError, wantHTTPStatus: 200},
+		{name: "Rate limited", mode: FailureRateLimited, wantShow: true, wantHTTPStatus: 200},
+		{name: "Empty meta", mode: FailureEmptyMeta, wantCreate: true, wantShow: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer()
+			defer server.Close()
+
+			client := privatebin.NewClient(server.Endpoint())
+			ctx := context.Background()
+			opts := privatebin.CreatePasteOptions{Compress: privatebin.CompressionAlgorithmNone}
+
+			created, err := client.CreatePaste(ctx, []byte("data"), opts)
+			require.NoError(t, err)
+
+			server.SetFailureMode(tt.mode)
+
+			_, err = client.CreatePaste(ctx, []byte("data"), opts)
+			if tt.wantCreate {
+				require.NoError(t, err)
+			} else {
+				var serverErr *privatebin.ServerError
+				require.ErrorAs(t, err, &serverErr)
+				assert.Equal(t, tt.wantHTTPStatus, serverErr.HTTPStatus)
+			}
+
+			_, err = client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{})
+			if tt.wantShow {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestServer_RateLimited(t *testing.T) {
+	server := NewServer(WithRateLimitSeconds(42))
+	defer server.Close()
+
+	server.SetFailureMode(FailureRateLimited)
+
+	client := privatebin.NewClient(server.Endpoint())
+
+	_, err := client.CreatePaste(context.Background(), []byte("data"), privatebin.CreatePasteOptions{})
+	require.ErrorIs(t, err, privatebin.ErrRateLimited)
+
+	var serverErr *privatebin.ServerError
+	require.ErrorAs(t, err, &serverErr)
+	assert.Equal(t, 42*time.Second, serverErr.RetryAfter)
+}
+
+func TestServer_Endpoint(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	endpoint := server.Endpoint()
+	want, err := url.Parse(server.URL + "/")
+	require.NoError(t, err)
+	assert.Equal(t, *want, endpoint)
+}
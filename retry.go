@@ -0,0 +1,209 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+type (
+	// RetryPolicy controls how failed requests are sent again. Reads are
+	// retried on any network error, server error (5xx) and rate limiting.
+	// Requests creating or deleting data are only retried when they
+	// cannot have reached the server: the connection could not be
+	// established or the traffic limiter rejected them.
+	RetryPolicy struct {
+		// MaxAttempts is the maximum number of attempts of a request, the
+		// first one included. Values below 2 disable retries.
+		MaxAttempts int
+
+		// InitialBackoff is the delay before the first retry. It doubles
+		// on every following retry, up to MaxBackoff, and a random jitter
+		// of up to half the delay is subtracted.
+		InitialBackoff time.Duration
+		MaxBackoff     time.Duration
+
+		// MaxRetryAfter is the longest delay requested by the server, with
+		// the Retry-After header or the traffic limiter message, that is
+		// waited for. Requests asking for more are not retried. Zero means
+		// no limit.
+		MaxRetryAfter time.Duration
+	}
+)
+
+// DefaultRetryPolicy returns a policy with 3 attempts and backoff delays
+// between 500ms and 30s, waiting at most 2 minutes for the server. Clients
+// created without WithRetryPolicy do not retry.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		MaxRetryAfter:  2 * time.Minute,
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry failed requests.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// do sends the request and decodes the response in v, retrying according
+// to the retry policy. Requests that are not idempotent are only retried
+// when the server did not process them.
+func (c *Client) do(req *http.Request, idempotent bool, v any) error {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.Body != nil {
+			if req.GetBody == nil {
+				return fmt.Errorf("cannot retry request: body cannot be replayed")
+			}
+
+			body, err := req.GetBody()
+			if err != nil {
+				return fmt.Errorf("cannot retry request: %w", err)
+			}
+
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		err := c.roundTrip(req, v)
+		if err == nil {
+			return nil
+		}
+
+		delay, retry := c.retryPolicy.retryDelay(attempt, err, idempotent)
+		if !retry {
+			return err
+		}
+
+		c.logger.Info("retrying http request", "attempt", attempt+1, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) roundTrip(req *http.Request, v any) error {
+	c.logger.Info("sending http request", "method", req.Method, "url", redactURL(req.URL))
+	c.logger.Debug("http request header", "header", c.redactHeader(req.Header))
+
+	start := time.Now()
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		c.logger.Info("http request failed", "duration", time.Since(start), "error", err)
+		return fmt.Errorf("cannot execute http request: %w", err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	err = decodeResponse(res, v, c.decryptPolicy.maxResponseSize())
+
+	args := []any{"duration", time.Since(start), "status", res.StatusCode}
+
+	var serverErr *ServerError
+	switch {
+	case err == nil:
+		args = append(args, "privatebin_status", 0)
+	case errors.As(err, &serverErr):
+		args = append(args, "privatebin_status", serverErr.Status, "privatebin_message", serverErr.Message)
+	default:
+		args = append(args, "error", err)
+	}
+
+	c.logger.Info("received http response", args...)
+
+	return err
+}
+
+// retryDelay reports whether the request that failed with err on the
+// given attempt must be retried, and how long to wait before.
+func (p RetryPolicy) retryDelay(attempt int, err error, idempotent bool) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	var (
+		serverErr  *ServerError
+		retryAfter time.Duration
+	)
+
+	switch {
+	case errors.Is(err, ErrPolicyViolation):
+		// The server would send the same oversized response again.
+		return 0, false
+	case errors.As(err, &serverErr):
+		switch {
+		case errors.Is(serverErr, ErrRateLimited):
+		case idempotent && serverErr.HTTPStatus >= 500 && serverErr.HTTPStatus != http.StatusNotImplemented:
+		default:
+			return 0, false
+		}
+
+		retryAfter = serverErr.RetryAfter
+	case idempotent || isDialError(err):
+	default:
+		return 0, false
+	}
+
+	if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+		return 0, false
+	}
+
+	return max(p.backoff(attempt), retryAfter), true
+}
+
+// backoff returns the exponential backoff delay after the given attempt,
+// with jitter.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d - rand.N(d/2+1)
+}
+
+// isDialError reports whether err happened while establishing the
+// connection, before anything was sent to the server.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial" || opErr.Op == "proxyconnect"
+	}
+
+	return false
+}
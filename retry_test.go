@@ -0,0 +1,200 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Retry(t *testing.T) {
+	const ok = `{"status":0,"id":"f468483c313401e8"}`
+
+	type response struct {
+		code       int
+		retryAfter string
+		body       string
+	}
+
+	unavailable := response{code: http.StatusServiceUnavailable, body: "down"}
+	rateLimited := response{
+		code: http.StatusOK,
+		body: `{"status":1,"message":"Please wait 0 seconds between each post."}`,
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		maxAttempts  int
+		responses    []response
+		wantAttempts int
+		wantErr      error
+	}{
+		{
+			name:         "Read retried on server error",
+			method:       http.MethodGet,
+			maxAttempts:  3,
+			responses:    []response{unavailable, unavailable, {code: http.StatusOK, body: ok}},
+			wantAttempts: 3,
+		},
+		{
+			name:         "Read gives up after max attempts",
+			method:       http.MethodGet,
+			maxAttempts:  2,
+			responses:    []response{unavailable, unavailable, {code: http.StatusOK, body: ok}},
+			wantAttempts: 2,
+			wantErr:      &ServerError{},
+		},
+		{
+			name:         "Create not retried on server error",
+			method:       http.MethodPost,
+			maxAttempts:  3,
+			responses:    []response{unavailable, {code: http.StatusOK, body: ok}},
+			wantAttempts: 1,
+			wantErr:      &ServerError{},
+		},
+		{
+			name:         "Create retried when rate limited",
+			method:       http.MethodPost,
+			maxAttempts:  3,
+			responses:    []response{rateLimited, {code: http.StatusOK, body: ok}},
+			wantAttempts: 2,
+		},
+		{
+			name:         "Retry-After above the limit",
+			method:       http.MethodGet,
+			maxAttempts:  3,
+			responses:    []response{{code: http.StatusTooManyRequests, retryAfter: "3600"}},
+			wantAttempts: 1,
+			wantErr:      ErrRateLimited,
+		},
+		{
+			name:         "Retries disabled",
+			method:       http.MethodGet,
+			responses:    []response{unavailable, {code: http.StatusOK, body: ok}},
+			wantAttempts: 1,
+			wantErr:      &ServerError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						body, err := io.ReadAll(r.Body)
+						require.NoError(t, err)
+						if r.Method == http.MethodPost {
+							assert.Equal(t, "payload", string(body))
+						}
+
+						res := tt.responses[attempts]
+						attempts++
+
+						if res.retryAfter != "" {
+							w.Header().Set("Retry-After", res.retryAfter)
+						}
+						w.WriteHeader(res.code)
+						_, _ = io.WriteString(w, res.body)
+					},
+				),
+			)
+			defer server.Close()
+
+			endpoint, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := NewClient(
+				*endpoint,
+				WithRetryPolicy(
+					RetryPolicy{
+						MaxAttempts:    tt.maxAttempts,
+						InitialBackoff: time.Millisecond,
+						MaxBackoff:     5 * time.Millisecond,
+						MaxRetryAfter:  time.Minute,
+					},
+				),
+			)
+
+			var body io.Reader
+			if tt.method == http.MethodPost {
+				body = strings.NewReader("payload")
+			}
+
+			req, err := client.newRequest(context.Background(), tt.method, server.URL, body)
+			require.NoError(t, err)
+
+			var v deletePasteResponse
+			err = client.do(req, tt.method == http.MethodGet, &v)
+			assert.Equal(t, tt.wantAttempts, attempts)
+
+			switch target := tt.wantErr.(type) {
+			case nil:
+				require.NoError(t, err)
+				assert.Equal(t, "f468483c313401e8", v.ID)
+			case *ServerError:
+				require.ErrorAs(t, err, &target)
+			default:
+				require.ErrorIs(t, err, target)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_RetryDelay(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	_, dialErr := http.Get("http://" + addr)
+	require.Error(t, dialErr)
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: 3 * time.Second}
+
+	_, retry := policy.retryDelay(1, dialErr, false)
+	assert.True(t, retry, "connection failures are retried for creates")
+
+	_, retry = policy.retryDelay(1, io.ErrUnexpectedEOF, false)
+	assert.False(t, retry, "other network errors are not retried for creates")
+
+	_, retry = policy.retryDelay(1, io.ErrUnexpectedEOF, true)
+	assert.True(t, retry, "network errors are retried for reads")
+
+	_, retry = policy.retryDelay(3, dialErr, true)
+	assert.False(t, retry, "attempts are bounded")
+
+	delay, retry := policy.retryDelay(1, &ServerError{HTTPStatus: http.StatusTooManyRequests, RetryAfter: 10 * time.Second}, false)
+	assert.True(t, retry)
+	assert.Equal(t, 10*time.Second, delay)
+
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		delay := policy.backoff(attempt + 1)
+		assert.LessOrEqual(t, delay, want)
+		assert.GreaterOrEqual(t, delay, want/2)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"go.gearno.de/encoding/base58"
+)
+
+const (
+	masterKeySize = 32
+)
+
+type (
+	// MasterKey is the random key shared in the paste URL fragment. The
+	// server never sees it.
+	MasterKey []byte
+
+	// SealOptions are the paste settings covered by the authenticated data
+	// of an EncryptedPaste, plus the expiration sent along as metadata.
+	SealOptions struct {
+		Formatter        Formatter
+		Expire           Expire
+		OpenDiscussion   bool
+		BurnAfterReading bool
+		Compress         CompressionAlgorithm
+		Password         []byte
+	}
+
+	// EncryptedPaste is a paste envelope as exchanged with a PrivateBin
+	// server, it marshals to the v2 JSON format.
+	EncryptedPaste struct {
+		V     int                `json:"v"`
+		AData AData              `json:"adata"`
+		Meta  EncryptedPasteMeta `json:"meta"`
+		CT    string             `json:"ct"`
+	}
+
+	EncryptedPasteMeta struct {
+		Expire string `json:"expire"`
+	}
+)
+
+// NewMasterKey returns a random master key.
+func NewMasterKey() (MasterKey, error) {
+	key, err := generateRandomBytes(masterKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate random bytes: %w", err)
+	}
+
+	return key, nil
+}
+
+// ParseMasterKey decodes a base58 master key as found in the fragment of
+// a paste URL, without the burn after reading "-" prefix.
+func ParseMasterKey(s string) (MasterKey, error) {
+	key, err := base58.Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode master key: %w", err)
+	}
+
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("invalid master key: expected %d bytes, got %d", masterKeySize, len(key))
+	}
+
+	return key, nil
+}
+
+func (k MasterKey) String() string {
+	return base58.Encode(k)
+}
+
+// withPassword returns the key material used for the key derivation.
+func (k MasterKey) withPassword(password []byte) []byte {
+	key := make([]byte, 0, len(k)+len(password))
+	key = append(key, k...)
+	return append(key, password...)
+}
+
+// Seal encrypts the paste with a new random master key. It does not
+// talk to any server, the envelope can be sent with any transport and
+// opened with Open.
+func Seal(paste Paste, opts SealOptions) (EncryptedPaste, MasterKey, error) {
+	return seal(paste, opts, discardLogger)
+}
+
+func seal(paste Paste, opts SealOptions, logger *slog.Logger) (EncryptedPaste, MasterKey, error) {
+	masterKey, err := NewMasterKey()
+	if err != nil {
+		return EncryptedPaste{}, nil, err
+	}
+
+	data, err := json.Marshal(&paste)
+	if err != nil {
+		return EncryptedPaste{}, nil, fmt.Errorf("cannot json marshal paste content: %w", err)
+	}
+
+	formatter, err := opts.Formatter.adataValue()
+	if err != nil {
+		return EncryptedPaste{}, nil, err
+	}
+
+	expire, err := opts.Expire.metaValue()
+	if err != nil {
+		return EncryptedPaste{}, nil, err
+	}
+
+	spec, err := newSpec(opts.Compress)
+	if err != nil {
+		return EncryptedPaste{}, nil, err
+	}
+
+	adata := AData{
+		spec,
+		formatter,
+		opts.OpenDiscussion,
+		opts.BurnAfterReading,
+	}
+
+	authData, err := json.Marshal(adata)
+	if err != nil {
+		return EncryptedPaste{}, nil, fmt.Errorf("cannot encode adata: %w", err)
+	}
+
+	cipherText, err := encrypt(masterKey.withPassword(opts.Password), data, authData, spec, logger)
+	if err != nil {
+		return EncryptedPaste{}, nil, fmt.Errorf("cannot encrypt data: %w", err)
+	}
+
+	return EncryptedPaste{
+		V:     apiVersion,
+		AData: adata,
+		Meta:  EncryptedPasteMeta{Expire: expire},
+		CT:    cipherText,
+	}, masterKey, nil
+}
+
+// Open decrypts an envelope produced by Seal or returned by a PrivateBin
+// server, enforcing the DefaultDecryptPolicy.
+func Open(encryptedPaste EncryptedPaste, masterKey MasterKey, password []byte) (Paste, error) {
+	return DefaultDecryptPolicy().Open(encryptedPaste, masterKey, password)
+}
+
+// Open is like the Open function but enforces p instead of the default
+// policy.
+func (p DecryptPolicy) Open(encryptedPaste EncryptedPaste, masterKey MasterKey, password []byte) (Paste, error) {
+	return p.open(encryptedPaste, masterKey, password, discardLogger)
+}
+
+func (p DecryptPolicy) open(encryptedPaste EncryptedPaste, masterKey MasterKey, password []byte, logger *slog.Logger) (Paste, error) {
+	if encryptedPaste.V != apiVersion {
+		return Paste{}, fmt.Errorf("unsupported paste version: %d", encryptedPaste.V)
+	}
+
+	authData, err := json.Marshal(encryptedPaste.AData)
+	if err != nil {
+		return Paste{}, fmt.Errorf("cannot encode adata: %w", err)
+	}
+
+	data, err := decrypt(
+		masterKey.withPassword(password),
+		encryptedPaste.CT,
+		authData,
+		encryptedPaste.AData.Spec,
+		p,
+		logger,
+	)
+	if err != nil {
+		return Paste{}, fmt.Errorf("cannot decrypt data: %w", err)
+	}
+
+	var paste Paste
+	if err := json.Unmarshal(data, &paste); err != nil {
+		return Paste{}, fmt.Errorf("cannot unmarshal paste content: %w", err)
+	}
+
+	return paste, nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	paste := Paste{
+		Data: []byte("hello world"),
+		Attachments: []Attachment{
+			{Name: "file.txt", Data: []byte("attachment")},
+		},
+	}
+
+	opts := SealOptions{
+		Formatter:        FormatterMarkdown,
+		Expire:           Expire1Day,
+		OpenDiscussion:   true,
+		BurnAfterReading: false,
+		Compress:         CompressionAlgorithmGZip,
+		Password:         []byte("secret"),
+	}
+
+	encryptedPaste, masterKey, err := Seal(paste, opts)
+	require.NoError(t, err)
+	assert.Len(t, masterKey, masterKeySize)
+	assert.Equal(t, apiVersion, encryptedPaste.V)
+	assert.Equal(t, "markdown", encryptedPaste.AData.Formatter)
+	assert.True(t, encryptedPaste.AData.OpenDiscussion)
+	assert.Equal(t, "1day", encryptedPaste.Meta.Expire)
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := Open(encryptedPaste, masterKey, []byte("secret"))
+		require.NoError(t, err)
+		assert.Equal(t, paste.Data, got.Data)
+		require.Len(t, got.Attachments, 1)
+		assert.Equal(t, paste.Attachments[0].Data, got.Attachments[0].Data)
+		assert.Equal(t, paste.Attachments[0].Name, got.Attachments[0].Name)
+		assert.Equal(t, "text/plain; charset=utf-8", got.Attachments[0].MimeType)
+	})
+
+	t.Run("round trip through json", func(t *testing.T) {
+		data, err := json.Marshal(encryptedPaste)
+		require.NoError(t, err)
+
+		var fields map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(data, &fields))
+		assert.Len(t, fields, 4)
+		assert.Contains(t, fields, "v")
+		assert.Contains(t, fields, "adata")
+		assert.Contains(t, fields, "meta")
+		assert.Contains(t, fields, "ct")
+		assert.JSONEq(t, `{"expire":"1day"}`, string(fields["meta"]))
+
+		var decoded EncryptedPaste
+		require.NoError(t, json.Unmarshal(data, &decoded))
+
+		got, err := Open(decoded, masterKey, []byte("secret"))
+		require.NoError(t, err)
+		assert.Equal(t, paste.Data, got.Data)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := Open(encryptedPaste, masterKey, []byte("wrong"))
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+
+	t.Run("tampered adata", func(t *testing.T) {
+		tampered := encryptedPaste
+		tampered.AData.BurnAfterReading = true
+
+		_, err := Open(tampered, masterKey, []byte("secret"))
+		assert.ErrorIs(t, err, ErrDecryptionFailed)
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		unsupported := encryptedPaste
+		unsupported.V = 1
+
+		_, err := Open(unsupported, masterKey, []byte("secret"))
+		assert.EqualError(t, err, "unsupported paste version: 1")
+	})
+
+	t.Run("policy violation", func(t *testing.T) {
+		policy := DefaultDecryptPolicy()
+		policy.MinIterations = iterationCount + 1
+
+		_, err := policy.Open(encryptedPaste, masterKey, []byte("secret"))
+		assert.ErrorIs(t, err, ErrPolicyViolation)
+	})
+}
+
+func TestParseMasterKey(t *testing.T) {
+	masterKey, err := NewMasterKey()
+	require.NoError(t, err)
+
+	parsed, err := ParseMasterKey(masterKey.String())
+	require.NoError(t, err)
+	assert.Equal(t, masterKey, parsed)
+
+	_, err = ParseMasterKey("0OIl")
+	assert.Error(t, err)
+
+	_, err = ParseMasterKey(MasterKey("too short").String())
+	assert.Error(t, err)
+}
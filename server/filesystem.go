@@ -0,0 +1,328 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// FilesystemStorage stores each paste as a JSON document in a two
+	// level directory tree derived from the paste identifier, e.g.
+	// "f4/68/f468483c313401e8.json". Comments are stored next to the
+	// paste in a "f468483c313401e8.discussion" directory.
+	FilesystemStorage struct {
+		dir string
+		mu  sync.RWMutex
+	}
+)
+
+var _ Storage = (*FilesystemStorage)(nil)
+
+func NewFilesystemStorage(dir string) (*FilesystemStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("cannot create storage directory: %w", err)
+	}
+
+	return &FilesystemStorage{dir: dir}, nil
+}
+
+func (s *FilesystemStorage) CreatePaste(ctx context.Context, paste *Paste) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.pastePath(paste.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return ErrAlreadyExists
+	}
+
+	return writeJSONFile(path, paste)
+}
+
+func (s *FilesystemStorage) ReadPaste(ctx context.Context, id string) (*Paste, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	path, err := s.pastePath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var paste Paste
+	if err := readJSONFile(path, &paste); err != nil {
+		return nil, err
+	}
+
+	return &paste, nil
+}
+
+func (s *FilesystemStorage) DeletePaste(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deletePaste(id)
+}
+
+func (s *FilesystemStorage) CreateComment(ctx context.Context, comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pastePath, err := s.pastePath(comment.PasteID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(pastePath); err != nil {
+		return ErrNotFound
+	}
+
+	if !isID(comment.ID) {
+		return fmt.Errorf("invalid comment id %q", comment.ID)
+	}
+
+	path := filepath.Join(discussionDir(pastePath), comment.ID+".json")
+	if _, err := os.Stat(path); err == nil {
+		return ErrAlreadyExists
+	}
+
+	return writeJSONFile(path, comment)
+}
+
+func (s *FilesystemStorage) ListComments(ctx context.Context, pasteID string) ([]*Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	pastePath, err := s.pastePath(pasteID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(pastePath); err != nil {
+		return nil, ErrNotFound
+	}
+
+	entries, err := os.ReadDir(discussionDir(pastePath))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []*Comment{}, nil
+		}
+
+		return nil, fmt.Errorf("cannot read discussion directory: %w", err)
+	}
+
+	comments := make([]*Comment, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		var comment Comment
+		if err := readJSONFile(filepath.Join(discussionDir(pastePath), entry.Name()), &comment); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, &comment)
+	}
+
+	sortComments(comments)
+
+	return comments, nil
+}
+
+// PurgeExpired walks the storage directory without holding the lock, files
+// being replaced atomically, and only locks the storage while deleting each
+// expired paste so concurrent requests are not blocked by the walk.
+func (s *FilesystemStorage) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	var expired []string
+	err := filepath.WalkDir(
+		s.dir,
+		func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				if strings.HasSuffix(d.Name(), ".discussion") {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			id := strings.TrimSuffix(d.Name(), ".json")
+			if !isID(id) {
+				return nil
+			}
+
+			var paste Paste
+			if err := readJSONFile(path, &paste); err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return ctx.Err()
+				}
+
+				return err
+			}
+
+			if paste.Expired(now) {
+				expired = append(expired, id)
+			}
+
+			return ctx.Err()
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("cannot walk storage directory: %w", err)
+	}
+
+	n := 0
+	for _, id := range expired {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		err := s.purgePaste(id, now)
+		switch {
+		case err == nil:
+			n++
+		case errors.Is(err, ErrNotFound):
+			// Deleted by a concurrent request.
+		default:
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// purgePaste deletes the paste if it is still expired, it may have been
+// deleted since the storage directory was walked.
+func (s *FilesystemStorage) purgePaste(id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path, err := s.pastePath(id)
+	if err != nil {
+		return err
+	}
+
+	var paste Paste
+	if err := readJSONFile(path, &paste); err != nil {
+		return err
+	}
+
+	if !paste.Expired(now) {
+		return ErrNotFound
+	}
+
+	return s.deletePaste(id)
+}
+
+func (s *FilesystemStorage) deletePaste(id string) error {
+	path, err := s.pastePath(id)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("cannot remove paste file: %w", err)
+	}
+
+	if err := os.RemoveAll(discussionDir(path)); err != nil {
+		return fmt.Errorf("cannot remove discussion directory: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FilesystemStorage) pastePath(id string) (string, error) {
+	if !isID(id) {
+		return "", fmt.Errorf("invalid paste id %q", id)
+	}
+
+	return filepath.Join(s.dir, id[0:2], id[2:4], id+".json"), nil
+}
+
+func discussionDir(pastePath string) string {
+	return strings.TrimSuffix(pastePath, ".json") + ".discussion"
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("cannot read %q: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("cannot decode %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// writeJSONFile writes the document to a temporary file first and renames it
+// so readers never observe a partially written file.
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cannot encode %q: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("cannot create %q directory: %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("cannot write temporary file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot close temporary file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot rename temporary file: %w", err)
+	}
+
+	return nil
+}
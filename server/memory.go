@@ -0,0 +1,146 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// MemoryStorage keeps pastes in memory. Everything is lost when the
+	// process exits.
+	MemoryStorage struct {
+		mu       sync.RWMutex
+		pastes   map[string]Paste
+		comments map[string][]Comment
+	}
+)
+
+var _ Storage = (*MemoryStorage)(nil)
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		pastes:   make(map[string]Paste),
+		comments: make(map[string][]Comment),
+	}
+}
+
+func (s *MemoryStorage) CreatePaste(ctx context.Context, paste *Paste) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pastes[paste.ID]; ok {
+		return ErrAlreadyExists
+	}
+
+	s.pastes[paste.ID] = *paste
+
+	return nil
+}
+
+func (s *MemoryStorage) ReadPaste(ctx context.Context, id string) (*Paste, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paste, ok := s.pastes[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return &paste, nil
+}
+
+func (s *MemoryStorage) DeletePaste(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pastes[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.pastes, id)
+	delete(s.comments, id)
+
+	return nil
+}
+
+func (s *MemoryStorage) CreateComment(ctx context.Context, comment *Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pastes[comment.PasteID]; !ok {
+		return ErrNotFound
+	}
+
+	for _, c := range s.comments[comment.PasteID] {
+		if c.ID == comment.ID {
+			return ErrAlreadyExists
+		}
+	}
+
+	s.comments[comment.PasteID] = append(s.comments[comment.PasteID], *comment)
+
+	return nil
+}
+
+func (s *MemoryStorage) ListComments(ctx context.Context, pasteID string) ([]*Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.pastes[pasteID]; !ok {
+		return nil, ErrNotFound
+	}
+
+	comments := make([]*Comment, 0, len(s.comments[pasteID]))
+	for _, c := range s.comments[pasteID] {
+		comments = append(comments, &c)
+	}
+
+	sortComments(comments)
+
+	return comments, nil
+}
+
+func (s *MemoryStorage) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, paste := range s.pastes {
+		if paste.Expired(now) {
+			delete(s.pastes, id)
+			delete(s.comments, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func sortComments(comments []*Comment) {
+	sort.SliceStable(
+		comments,
+		func(i, j int) bool {
+			if comments[i].Created.Equal(comments[j].Created) {
+				return comments[i].ID < comments[j].ID
+			}
+
+			return comments[i].Created.Before(comments[j].Created)
+		},
+	)
+}
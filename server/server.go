@@ -0,0 +1,568 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+// Package server implements the PrivateBin v2 JSON API.
+//
+// The server never sees the master key of the pastes it stores: the
+// encrypted envelopes sent by the clients are kept as is in a Storage and
+// sent back on read. Delete tokens are computed the same way as the PHP
+// implementation, an HMAC-SHA256 of the paste identifier keyed with a
+// random salt stored alongside the paste.
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.gearno.de/privatebin/v2"
+)
+
+const (
+	apiVersion = 2
+
+	defaultSizeLimit = 10 * 1024 * 1024
+
+	msgPasteNotFound  = "Paste does not exist, has expired or has been deleted."
+	msgInvalidData    = "Invalid data."
+	msgInvalidPasteID = "Invalid paste ID."
+	msgWrongToken     = "Wrong deletion token. Paste was not deleted."
+	msgSizeLimit      = "Paste is limited to %d bytes of encrypted data."
+	msgInternalError  = "Error processing request."
+)
+
+type (
+	Server struct {
+		storage       Storage
+		now           func() time.Time
+		defaultExpire privatebin.Expire
+		sizeLimit     int
+		discussion    bool
+	}
+
+	Option func(s *Server)
+
+	postRequest struct {
+		V           int             `json:"v"`
+		AData       json.RawMessage `json:"adata"`
+		Meta        postRequestMeta `json:"meta"`
+		CT          string          `json:"ct"`
+		PasteID     string          `json:"pasteid"`
+		ParentID    string          `json:"parentid"`
+		DeleteToken string          `json:"deletetoken"`
+	}
+
+	postRequestMeta struct {
+		Expire string `json:"expire"`
+	}
+
+	postResponse struct {
+		Status      int    `json:"status"`
+		ID          string `json:"id"`
+		URL         string `json:"url"`
+		DeleteToken string `json:"deletetoken,omitempty"`
+	}
+
+	errorResponse struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+
+	readResponse struct {
+		Status        int                   `json:"status"`
+		ID            string                `json:"id"`
+		URL           string                `json:"url"`
+		V             int                   `json:"v"`
+		AData         json.RawMessage       `json:"adata"`
+		Meta          readResponseMeta      `json:"meta"`
+		CT            string                `json:"ct"`
+		Comments      []readResponseComment `json:"comments"`
+		CommentCount  int                   `json:"comment_count"`
+		CommentOffset int                   `json:"comment_offset"`
+		Context       string                `json:"@context"`
+	}
+
+	readResponseMeta struct {
+		Created    int64 `json:"created"`
+		TimeToLive int64 `json:"time_to_live,omitempty"`
+	}
+
+	readResponseComment struct {
+		ID       string                  `json:"id"`
+		PasteID  string                  `json:"pasteid"`
+		ParentID string                  `json:"parentid"`
+		URL      string                  `json:"url"`
+		V        int                     `json:"v"`
+		CT       string                  `json:"ct"`
+		AData    json.RawMessage         `json:"adata"`
+		Meta     readResponseCommentMeta `json:"meta"`
+	}
+
+	readResponseCommentMeta struct {
+		Created int64 `json:"created"`
+	}
+)
+
+// WithClock replaces the clock used to timestamp pastes and decide whether
+// they have expired.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithDefaultExpire sets the expire option applied when the client sends
+// an unknown or empty value. It defaults to privatebin.Expire1Week, like
+// PrivateBin.
+func WithDefaultExpire(expire privatebin.Expire) Option {
+	return func(s *Server) {
+		s.defaultExpire = expire
+	}
+}
+
+// WithSizeLimit sets the maximum size in bytes of the encrypted data of a
+// paste or comment. It defaults to 10 MiB.
+func WithSizeLimit(n int) Option {
+	return func(s *Server) {
+		s.sizeLimit = n
+	}
+}
+
+// WithDiscussion enables or disables comments on the whole instance. It is
+// enabled by default.
+func WithDiscussion(enabled bool) Option {
+	return func(s *Server) {
+		s.discussion = enabled
+	}
+}
+
+func New(storage Storage, options ...Option) *Server {
+	s := &Server{
+		storage:       storage,
+		now:           time.Now,
+		defaultExpire: privatebin.Expire1Week,
+		sizeLimit:     defaultSizeLimit,
+		discussion:    true,
+	}
+
+	for _, option := range options {
+		option(s)
+	}
+
+	return s
+}
+
+// DeleteToken returns the token required to delete the paste.
+func DeleteToken(paste *Paste) string {
+	mac := hmac.New(sha256.New, []byte(paste.Salt))
+	_, _ = mac.Write([]byte(paste.ID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReadPaste returns the paste when it exists and has not expired.
+func (s *Server) ReadPaste(ctx context.Context, id string) (*Paste, error) {
+	if !isID(id) {
+		return nil, ErrNotFound
+	}
+
+	paste, err := s.storage.ReadPaste(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if paste.Expired(s.now()) {
+		if err := s.storage.DeletePaste(ctx, id); err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+
+		return nil, ErrNotFound
+	}
+
+	return paste, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Requested-With") != "JSONHttpRequest" {
+		writeHTML(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.read(w, r)
+	case http.MethodPost:
+		s.post(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) read(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id := r.URL.Query().Get("pasteid")
+	if id == "" {
+		id = r.URL.RawQuery
+	}
+
+	if !isID(id) {
+		writeError(w, msgInvalidPasteID)
+		return
+	}
+
+	paste, err := s.ReadPaste(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, msgPasteNotFound)
+			return
+		}
+
+		writeInternalError(w)
+		return
+	}
+
+	comments, err := s.storage.ListComments(ctx, id)
+	if err != nil {
+		writeInternalError(w)
+		return
+	}
+
+	res := readResponse{
+		ID:       paste.ID,
+		URL:      "?" + paste.ID,
+		V:        paste.V,
+		AData:    paste.AData,
+		Meta:     readResponseMeta{Created: paste.Created.Unix()},
+		CT:       paste.CT,
+		Comments: make([]readResponseComment, 0, len(comments)),
+		Context:  "?jsonld=paste",
+	}
+
+	if !paste.ExpireAt.IsZero() {
+		res.Meta.TimeToLive = int64(paste.ExpireAt.Sub(s.now()) / time.Second)
+	}
+
+	for _, c := range comments {
+		res.Comments = append(
+			res.Comments,
+			readResponseComment{
+				ID:       c.ID,
+				PasteID:  c.PasteID,
+				ParentID: c.ParentID,
+				URL:      "?" + c.PasteID + "#comment-" + c.ID,
+				V:        c.V,
+				CT:       c.CT,
+				AData:    c.AData,
+				Meta:     readResponseCommentMeta{Created: c.Created.Unix()},
+			},
+		)
+	}
+
+	res.CommentCount = len(res.Comments)
+
+	_, burnAfterReading, err := paste.Flags()
+	if err != nil {
+		writeInternalError(w)
+		return
+	}
+
+	if burnAfterReading {
+		if err := s.storage.DeletePaste(ctx, id); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				// Another reader burned the paste first.
+				writeError(w, msgPasteNotFound)
+				return
+			}
+
+			writeInternalError(w)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) post(w http.ResponseWriter, r *http.Request) {
+	// The cipher text is base64 encoded and sent along with the adata and
+	// meta fields, leave some room above the size limit. One more byte is
+	// read to reject larger bodies instead of decoding a truncated one.
+	maxBodySize := int64(s.sizeLimit)*4/3 + 64*1024
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		writeError(w, msgInvalidData)
+		return
+	}
+
+	if int64(len(body)) > maxBodySize {
+		writeError(w, fmt.Sprintf(msgSizeLimit, s.sizeLimit))
+		return
+	}
+
+	var req postRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, msgInvalidData)
+		return
+	}
+
+	switch {
+	case req.DeleteToken != "":
+		s.deletePaste(w, r, req)
+	case req.PasteID != "":
+		s.createComment(w, r, req)
+	default:
+		s.createPaste(w, r, req)
+	}
+}
+
+func (s *Server) createPaste(w http.ResponseWriter, r *http.Request, req postRequest) {
+	if req.V != apiVersion || req.CT == "" {
+		writeError(w, msgInvalidData)
+		return
+	}
+
+	if len(req.CT) > s.sizeLimit {
+		writeError(w, fmt.Sprintf(msgSizeLimit, s.sizeLimit))
+		return
+	}
+
+	now := s.now()
+	paste := &Paste{
+		ID:      newID(),
+		V:       req.V,
+		AData:   req.AData,
+		CT:      req.CT,
+		Salt:    newSalt(),
+		Created: now,
+	}
+
+	// The adata is stored as is, only the flags the server acts upon
+	// are read.
+	openDiscussion, burnAfterReading, err := paste.Flags()
+	if err != nil || openDiscussion && (burnAfterReading || !s.discussion) {
+		writeError(w, msgInvalidData)
+		return
+	}
+
+	expire, err := privatebin.ParseExpire(req.Meta.Expire)
+	if err != nil {
+		expire = s.defaultExpire
+	}
+
+	if ttl := expire.Duration(); ttl > 0 {
+		paste.ExpireAt = now.Add(ttl)
+	}
+
+	if err := s.storage.CreatePaste(r.Context(), paste); err != nil {
+		writeInternalError(w)
+		return
+	}
+
+	writeJSON(
+		w,
+		http.StatusOK,
+		postResponse{
+			ID:          paste.ID,
+			URL:         "?" + paste.ID,
+			DeleteToken: DeleteToken(paste),
+		},
+	)
+}
+
+func (s *Server) createComment(w http.ResponseWriter, r *http.Request, req postRequest) {
+	ctx := r.Context()
+
+	if !isID(req.PasteID) {
+		writeError(w, msgInvalidPasteID)
+		return
+	}
+
+	paste, err := s.ReadPaste(ctx, req.PasteID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, msgPasteNotFound)
+			return
+		}
+
+		writeInternalError(w)
+		return
+	}
+
+	openDiscussion, _, err := paste.Flags()
+	if err != nil {
+		writeInternalError(w)
+		return
+	}
+
+	if !s.discussion || !openDiscussion {
+		writeError(w, msgInvalidData)
+		return
+	}
+
+	// The comment adata is stored as is, it only has to be an array.
+	var spec []json.RawMessage
+	if req.V != apiVersion || req.CT == "" || json.Unmarshal(req.AData, &spec) != nil {
+		writeError(w, msgInvalidData)
+		return
+	}
+
+	if len(req.CT) > s.sizeLimit {
+		writeError(w, fmt.Sprintf(msgSizeLimit, s.sizeLimit))
+		return
+	}
+
+	if req.ParentID != paste.ID {
+		comments, err := s.storage.ListComments(ctx, paste.ID)
+		if err != nil {
+			writeInternalError(w)
+			return
+		}
+
+		found := false
+		for _, c := range comments {
+			if c.ID == req.ParentID {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			writeError(w, msgInvalidData)
+			return
+		}
+	}
+
+	comment := &Comment{
+		ID:       newID(),
+		PasteID:  paste.ID,
+		ParentID: req.ParentID,
+		V:        req.V,
+		AData:    req.AData,
+		CT:       req.CT,
+		Created:  s.now(),
+	}
+
+	if err := s.storage.CreateComment(ctx, comment); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, msgPasteNotFound)
+			return
+		}
+
+		writeInternalError(w)
+		return
+	}
+
+	writeJSON(
+		w,
+		http.StatusOK,
+		postResponse{
+			ID:  comment.ID,
+			URL: "?" + paste.ID + "#comment-" + comment.ID,
+		},
+	)
+}
+
+func (s *Server) deletePaste(w http.ResponseWriter, r *http.Request, req postRequest) {
+	ctx := r.Context()
+
+	if !isID(req.PasteID) {
+		writeError(w, msgInvalidPasteID)
+		return
+	}
+
+	paste, err := s.ReadPaste(ctx, req.PasteID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			writeError(w, msgPasteNotFound)
+			return
+		}
+
+		writeInternalError(w)
+		return
+	}
+
+	if !hmac.Equal([]byte(req.DeleteToken), []byte(DeleteToken(paste))) {
+		writeError(w, msgWrongToken)
+		return
+	}
+
+	if err := s.storage.DeletePaste(ctx, paste.ID); err != nil && !errors.Is(err, ErrNotFound) {
+		writeInternalError(w)
+		return
+	}
+
+	writeJSON(
+		w,
+		http.StatusOK,
+		postResponse{
+			ID:  paste.ID,
+			URL: "?" + paste.ID,
+		},
+	)
+}
+
+func writeError(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, errorResponse{Status: 1, Message: message})
+}
+
+func writeInternalError(w http.ResponseWriter) {
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Status: 1, Message: msgInternalError})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeHTML(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	_, _ = io.WriteString(w, "<!DOCTYPE html><html><head><title>PrivateBin</title></head><body><p>This PrivateBin instance only serves the JSON API.</p></body></html>\n")
+}
+
+// isID reports whether s is a paste or comment identifier: 16 lowercase
+// hexadecimal characters.
+func isID(s string) bool {
+	if len(s) != 16 {
+		return false
+	}
+
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func newSalt() string {
+	b := make([]byte, 256)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
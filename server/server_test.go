@@ -0,0 +1,364 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.gearno.de/privatebin/v2"
+)
+
+func newTestServer(t *testing.T, options ...Option) (*privatebin.Client, *MemoryStorage) {
+	storage := NewMemoryStorage()
+	ts := httptest.NewServer(New(storage, options...))
+	t.Cleanup(ts.Close)
+
+	endpoint, err := url.Parse(ts.URL + "/")
+	require.NoError(t, err)
+
+	return privatebin.NewClient(*endpoint), storage
+}
+
+func TestServer_PasteLifecycle(t *testing.T) {
+	client, storage := newTestServer(t)
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(
+		ctx,
+		[]byte("hello"),
+		privatebin.CreatePasteOptions{
+			Expire:         privatebin.Expire1Day,
+			OpenDiscussion: true,
+			Compress:       privatebin.CompressionAlgorithmGZip,
+		},
+	)
+	require.NoError(t, err)
+
+	paste, err := storage.ReadPaste(ctx, created.PasteID)
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, paste.ExpireAt.Sub(paste.Created))
+
+	mac := hmac.New(sha256.New, []byte(paste.Salt))
+	_, _ = mac.Write([]byte(paste.ID))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), created.DeleteToken)
+
+	comment, err := client.CreateComment(ctx, created.PasteURL, "", "alice", "hi", privatebin.CreateCommentOptions{})
+	require.NoError(t, err)
+
+	_, err = client.CreateComment(ctx, created.PasteURL, comment.CommentID, "bob", "hey", privatebin.CreateCommentOptions{})
+	require.NoError(t, err)
+
+	_, err = client.CreateComment(ctx, created.PasteURL, "ffffffffffffffff", "eve", "?", privatebin.CreateCommentOptions{})
+	require.Error(t, err)
+
+	shown, err := client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(shown.Paste.Data))
+	require.Len(t, shown.Comments, 2)
+	assert.Equal(t, comment.CommentID, shown.Comments[1].ParentID)
+
+	require.ErrorIs(t, client.DeletePaste(ctx, created.PasteID, "bad"), privatebin.ErrInvalidDeleteToken)
+	require.NoError(t, client.DeletePaste(ctx, created.PasteID, created.DeleteToken))
+
+	_, err = storage.ReadPaste(ctx, created.PasteID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestServer_Expire(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expire  string
+		options []Option
+		want    time.Duration
+	}{
+		{name: "Known option", expire: "5min", want: 5 * time.Minute},
+		{name: "Never", expire: "never", want: 0},
+		{name: "Unknown option", expire: "1days", want: 7 * 24 * time.Hour},
+		{name: "Custom default", expire: "", options: []Option{WithDefaultExpire(privatebin.Expire1Hour)}, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := append([]Option{WithClock(func() time.Time { return now })}, tt.options...)
+			storage := NewMemoryStorage()
+			handler := New(storage, options...)
+
+			// The client only sends supported options, post the raw
+			// envelope to exercise the server fallback.
+			encryptedPaste, _, err := privatebin.Seal(
+				privatebin.Paste{Data: []byte("data")},
+				privatebin.SealOptions{},
+			)
+			require.NoError(t, err)
+			encryptedPaste.Meta.Expire = tt.expire
+
+			body, err := json.Marshal(encryptedPaste)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set("X-Requested-With", "JSONHttpRequest")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			var created struct {
+				Status int    `json:"status"`
+				ID     string `json:"id"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+			require.Equal(t, 0, created.Status)
+
+			paste, err := storage.ReadPaste(context.Background(), created.ID)
+			require.NoError(t, err)
+
+			if tt.want == 0 {
+				assert.True(t, paste.ExpireAt.IsZero())
+			} else {
+				assert.Equal(t, now.Add(tt.want), paste.ExpireAt)
+			}
+		})
+	}
+}
+
+func TestServer_ExpiredPaste(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	client, storage := newTestServer(t, WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(ctx, []byte("data"), privatebin.CreatePasteOptions{Expire: privatebin.Expire5Min})
+	require.NoError(t, err)
+
+	now = now.Add(5 * time.Minute)
+
+	_, err = client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{})
+	require.Error(t, err)
+
+	_, err = storage.ReadPaste(ctx, created.PasteID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestServer_BurnAfterReading(t *testing.T) {
+	client, storage := newTestServer(t)
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(ctx, []byte("data"), privatebin.CreatePasteOptions{BurnAfterReading: true})
+	require.NoError(t, err)
+
+	_, err = client.ShowPaste(ctx, created.PasteURL, privatebin.ShowPasteOptions{ConfirmBurn: true})
+	require.NoError(t, err)
+
+	_, err = storage.ReadPaste(ctx, created.PasteID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestServer_RejectedPastes(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		paste   privatebin.CreatePasteOptions
+		data    []byte
+	}{
+		{
+			name:  "Open discussion with burn after reading",
+			paste: privatebin.CreatePasteOptions{OpenDiscussion: true, BurnAfterReading: true},
+		},
+		{
+			name:    "Open discussion on instance without discussion",
+			options: []Option{WithDiscussion(false)},
+			paste:   privatebin.CreatePasteOptions{OpenDiscussion: true},
+		},
+		{
+			name:    "Paste above size limit",
+			options: []Option{WithSizeLimit(64)},
+			data:    []byte(strings.Repeat("a", 128)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := newTestServer(t, tt.options...)
+
+			_, err := client.CreatePaste(context.Background(), tt.data, tt.paste)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestServer_OversizedBody(t *testing.T) {
+	// A body above the read limit must be rejected, not truncated and
+	// reported as invalid JSON.
+	body := `{"v":2,"ct":"` + strings.Repeat("a", 128*1024) + `"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("X-Requested-With", "JSONHttpRequest")
+
+	rec := httptest.NewRecorder()
+	New(NewMemoryStorage(), WithSizeLimit(64)).ServeHTTP(rec, req)
+
+	assert.Contains(t, rec.Body.String(), `"message":"Paste is limited to 64 bytes of encrypted data."`)
+}
+
+func TestServer_OpaqueAData(t *testing.T) {
+	handler := New(NewMemoryStorage())
+
+	do := func(t *testing.T, method, target, body string) map[string]json.RawMessage {
+		t.Helper()
+
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("X-Requested-With", "JSONHttpRequest")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var res map[string]json.RawMessage
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.JSONEq(t, "0", string(res["status"]), rec.Body.String())
+
+		return res
+	}
+
+	// Unpadded base64, an unknown compression and an extra field must
+	// come back untouched.
+	spec := `["aXYAAAAAAAAAAAAA","c2FsdA",100000,256,128,"aes","gcm","zstd","extra"]`
+	adata := `[` + spec + `,"plaintext",1,0]`
+
+	created := do(t, http.MethodPost, "/", `{"v":2,"adata":`+adata+`,"ct":"Y3Q=","meta":{"expire":"1day"}}`)
+
+	var id string
+	require.NoError(t, json.Unmarshal(created["id"], &id))
+
+	do(t, http.MethodPost, "/", `{"v":2,"adata":`+spec+`,"ct":"Y3Q=","pasteid":"`+id+`","parentid":"`+id+`"}`)
+
+	read := do(t, http.MethodGet, "/?pasteid="+id, "")
+	assert.JSONEq(t, adata, string(read["adata"]))
+
+	var comments []map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(read["comments"], &comments))
+	require.Len(t, comments, 1)
+	assert.JSONEq(t, spec, string(comments[0]["adata"]))
+}
+
+func TestServer_InvalidAData(t *testing.T) {
+	tests := []struct {
+		name  string
+		adata string
+	}{
+		{name: "Missing", adata: `null`},
+		{name: "Not an array", adata: `{"spec":[]}`},
+		{name: "Missing flags", adata: `[[],"plaintext"]`},
+		{name: "Invalid flag", adata: `[[],"plaintext","yes",0]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"v":2,"adata":` + tt.adata + `,"ct":"Y3Q="}`
+
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set("X-Requested-With", "JSONHttpRequest")
+
+			rec := httptest.NewRecorder()
+			New(NewMemoryStorage()).ServeHTTP(rec, req)
+
+			assert.Contains(t, rec.Body.String(), `"message":"Invalid data."`)
+		})
+	}
+}
+
+func TestServer_CommentOnClosedDiscussion(t *testing.T) {
+	client, _ := newTestServer(t)
+	ctx := context.Background()
+
+	created, err := client.CreatePaste(ctx, []byte("data"), privatebin.CreatePasteOptions{})
+	require.NoError(t, err)
+
+	_, err = client.CreateComment(ctx, created.PasteURL, "", "", "hi", privatebin.CreateCommentOptions{})
+	require.Error(t, err)
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		json        bool
+		wantCode    int
+		wantContent string
+	}{
+		{
+			name:        "HTML page without JSON header",
+			method:      http.MethodGet,
+			target:      "/",
+			wantCode:    http.StatusOK,
+			wantContent: "<!DOCTYPE html>",
+		},
+		{
+			name:        "Invalid paste id",
+			method:      http.MethodGet,
+			target:      "/?../../etc/passwd",
+			json:        true,
+			wantCode:    http.StatusOK,
+			wantContent: `"message":"Invalid paste ID."`,
+		},
+		{
+			name:        "Unknown paste",
+			method:      http.MethodGet,
+			target:      "/?pasteid=f468483c313401e8",
+			json:        true,
+			wantCode:    http.StatusOK,
+			wantContent: `"message":"Paste does not exist, has expired or has been deleted."`,
+		},
+		{
+			name:     "Unsupported method",
+			method:   http.MethodPut,
+			target:   "/",
+			json:     true,
+			wantCode: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.json {
+				req.Header.Set("X-Requested-With", "JSONHttpRequest")
+			}
+
+			rec := httptest.NewRecorder()
+			New(NewMemoryStorage()).ServeHTTP(rec, req)
+
+			body, err := io.ReadAll(rec.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, string(body), tt.wantContent)
+		})
+	}
+}
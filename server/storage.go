@@ -0,0 +1,97 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+type (
+	// Storage persists pastes and their comments. Implementations only
+	// ever see encrypted data and must be safe for concurrent use.
+	Storage interface {
+		CreatePaste(ctx context.Context, paste *Paste) error
+		ReadPaste(ctx context.Context, id string) (*Paste, error)
+		DeletePaste(ctx context.Context, id string) error
+
+		CreateComment(ctx context.Context, comment *Comment) error
+		ListComments(ctx context.Context, pasteID string) ([]*Comment, error)
+
+		// PurgeExpired deletes every paste that expired before now and
+		// returns the number of deleted pastes.
+		PurgeExpired(ctx context.Context, now time.Time) (int, error)
+	}
+
+	// Paste is an encrypted paste. AData is stored as sent by the client,
+	// the server only reads its discussion and burn after reading flags.
+	Paste struct {
+		ID       string          `json:"id"`
+		V        int             `json:"v"`
+		AData    json.RawMessage `json:"adata"`
+		CT       string          `json:"ct"`
+		Salt     string          `json:"salt"`
+		Created  time.Time       `json:"created"`
+		ExpireAt time.Time       `json:"expire_at"`
+	}
+
+	// Comment is an encrypted comment. AData is stored as sent by the
+	// client.
+	Comment struct {
+		ID       string          `json:"id"`
+		PasteID  string          `json:"pasteid"`
+		ParentID string          `json:"parentid"`
+		V        int             `json:"v"`
+		AData    json.RawMessage `json:"adata"`
+		CT       string          `json:"ct"`
+		Created  time.Time       `json:"created"`
+	}
+)
+
+// Expired reports whether the paste has expired at the given time. Pastes
+// without expiration date never expire.
+func (p *Paste) Expired(now time.Time) bool {
+	return !p.ExpireAt.IsZero() && !now.Before(p.ExpireAt)
+}
+
+// Flags returns the open discussion and burn after reading flags of the
+// paste adata, the last two elements of the v2 adata array.
+func (p *Paste) Flags() (openDiscussion, burnAfterReading bool, err error) {
+	var values []json.RawMessage
+	if err := json.Unmarshal(p.AData, &values); err != nil {
+		return false, false, fmt.Errorf("cannot decode adata: %w", err)
+	}
+
+	if len(values) != 4 {
+		return false, false, fmt.Errorf("cannot decode adata: expected 4 elements, got %d", len(values))
+	}
+
+	var flags [2]int
+	for i, v := range values[2:] {
+		if err := json.Unmarshal(v, &flags[i]); err != nil {
+			return false, false, fmt.Errorf("cannot decode adata flag: %w", err)
+		}
+	}
+
+	return flags[0] != 0, flags[1] != 0, nil
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorage(t *testing.T) {
+	storages := []struct {
+		name string
+		new  func(t *testing.T) Storage
+	}{
+		{
+			name: "Memory",
+			new:  func(t *testing.T) Storage { return NewMemoryStorage() },
+		},
+		{
+			name: "Filesystem",
+			new: func(t *testing.T) Storage {
+				s, err := NewFilesystemStorage(t.TempDir())
+				require.NoError(t, err)
+				return s
+			},
+		},
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, storage := range storages {
+		t.Run(storage.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("Paste lifecycle", func(t *testing.T) {
+				s := storage.new(t)
+				paste := &Paste{ID: "f468483c313401e8", V: 2, CT: "ct", Salt: "salt", Created: now}
+
+				require.NoError(t, s.CreatePaste(ctx, paste))
+				require.ErrorIs(t, s.CreatePaste(ctx, paste), ErrAlreadyExists)
+
+				got, err := s.ReadPaste(ctx, paste.ID)
+				require.NoError(t, err)
+				assert.Equal(t, paste.ID, got.ID)
+				assert.Equal(t, paste.CT, got.CT)
+				assert.Equal(t, paste.Salt, got.Salt)
+				assert.True(t, paste.Created.Equal(got.Created))
+
+				require.NoError(t, s.DeletePaste(ctx, paste.ID))
+				require.ErrorIs(t, s.DeletePaste(ctx, paste.ID), ErrNotFound)
+
+				_, err = s.ReadPaste(ctx, paste.ID)
+				require.ErrorIs(t, err, ErrNotFound)
+			})
+
+			t.Run("Comments", func(t *testing.T) {
+				s := storage.new(t)
+				paste := &Paste{ID: "f468483c313401e8", V: 2, CT: "ct", Created: now}
+
+				comment := &Comment{ID: "0011223344556677", PasteID: paste.ID, ParentID: paste.ID, CT: "a", Created: now.Add(time.Second)}
+				require.ErrorIs(t, s.CreateComment(ctx, comment), ErrNotFound)
+
+				require.NoError(t, s.CreatePaste(ctx, paste))
+
+				comments, err := s.ListComments(ctx, paste.ID)
+				require.NoError(t, err)
+				assert.Empty(t, comments)
+
+				reply := &Comment{ID: "0000000000000001", PasteID: paste.ID, ParentID: comment.ID, CT: "b", Created: now.Add(2 * time.Second)}
+				require.NoError(t, s.CreateComment(ctx, reply))
+				require.NoError(t, s.CreateComment(ctx, comment))
+				require.ErrorIs(t, s.CreateComment(ctx, comment), ErrAlreadyExists)
+
+				comments, err = s.ListComments(ctx, paste.ID)
+				require.NoError(t, err)
+				require.Len(t, comments, 2)
+				assert.Equal(t, comment.ID, comments[0].ID)
+				assert.Equal(t, reply.ID, comments[1].ID)
+
+				require.NoError(t, s.DeletePaste(ctx, paste.ID))
+
+				_, err = s.ListComments(ctx, paste.ID)
+				require.ErrorIs(t, err, ErrNotFound)
+			})
+
+			t.Run("Purge expired", func(t *testing.T) {
+				s := storage.new(t)
+
+				pastes := []*Paste{
+					{ID: "0000000000000001", Created: now, ExpireAt: now.Add(time.Minute)},
+					{ID: "0000000000000002", Created: now, ExpireAt: now.Add(time.Hour)},
+					{ID: "0000000000000003", Created: now},
+				}
+
+				for _, paste := range pastes {
+					require.NoError(t, s.CreatePaste(ctx, paste))
+				}
+
+				n, err := s.PurgeExpired(ctx, now.Add(10*time.Minute))
+				require.NoError(t, err)
+				assert.Equal(t, 1, n)
+
+				_, err = s.ReadPaste(ctx, "0000000000000001")
+				require.ErrorIs(t, err, ErrNotFound)
+
+				_, err = s.ReadPaste(ctx, "0000000000000002")
+				require.NoError(t, err)
+
+				_, err = s.ReadPaste(ctx, "0000000000000003")
+				require.NoError(t, err)
+			})
+		})
+	}
+}
+
+func TestFilesystemStorage_PurgeExpiredConcurrentDelete(t *testing.T) {
+	s, err := NewFilesystemStorage(t.TempDir())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 50 {
+		paste := &Paste{ID: fmt.Sprintf("%016x", i+1), Created: now, ExpireAt: now.Add(time.Minute)}
+		require.NoError(t, s.CreatePaste(ctx, paste))
+	}
+
+	// Pastes deleted while the purge runs are not reported as errors.
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		for i := range 50 {
+			_ = s.DeletePaste(ctx, fmt.Sprintf("%016x", i+1))
+		}
+	})
+
+	n, err := s.PurgeExpired(ctx, now.Add(time.Hour))
+	wg.Wait()
+	require.NoError(t, err)
+	assert.LessOrEqual(t, n, 50)
+
+	n, err = s.PurgeExpired(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestFilesystemStorage_InvalidID(t *testing.T) {
+	s, err := NewFilesystemStorage(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = s.ReadPaste(context.Background(), "../../etc/passwd")
+	require.Error(t, err)
+
+	err = s.CreatePaste(context.Background(), &Paste{ID: "../0123456789ab"})
+	require.Error(t, err)
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+// CreatePasteFromReader is like CreatePaste but reads the paste text, or
+// the attachment when opts.AttachmentName is set, from r.
+//
+// The content is encoded and compressed while it is read, encrypted in
+// place and base64 encoded while the request body is sent, so only about
+// one copy of the encoded paste is held in memory instead of one per
+// step. Reading stops with ErrPasteTooLarge once opts.MaxSize bytes have
+// been read.
+func (c *Client) CreatePasteFromReader(
+	ctx context.Context,
+	r io.Reader,
+	opts CreatePasteOptions,
+) (*CreatePasteResult, error) {
+	size := sizeHint(r)
+	if opts.MaxSize > 0 && size > opts.MaxSize {
+		return nil, errPasteTooLarge(opts.MaxSize)
+	}
+
+	formatter, err := opts.Formatter.adataValue()
+	if err != nil {
+		return nil, err
+	}
+
+	expire, err := opts.Expire.metaValue()
+	if err != nil {
+		return nil, err
+	}
+
+	masterKey, err := NewMasterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := newSpec(opts.Compress)
+	if err != nil {
+		return nil, err
+	}
+
+	adata := AData{
+		spec,
+		formatter,
+		opts.OpenDiscussion,
+		opts.BurnAfterReading,
+	}
+
+	authData, err := json.Marshal(adata)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode adata: %w", err)
+	}
+
+	var plain bytes.Buffer
+	if size > 0 && spec.Compression == CompressionAlgorithmNone {
+		// Attachments grow by a third once base64 encoded.
+		plain.Grow(int(size/3*4) + 1024)
+	}
+
+	var w io.Writer = &plain
+	var fw *flate.Writer
+	if spec.Compression == CompressionAlgorithmGZip {
+		fw, err = flate.NewWriter(&plain, flate.BestCompression)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create new flate writer: %w", err)
+		}
+		w = fw
+	}
+
+	start := time.Now()
+	if err := writePasteJSON(w, r, opts); err != nil {
+		return nil, err
+	}
+
+	if fw != nil {
+		if err := fw.Close(); err != nil {
+			return nil, fmt.Errorf("cannot close flate writer: %w", err)
+		}
+	}
+	logDuration(c.logger, "encoding", start, "size", plain.Len(), "compression", spec.Compression)
+
+	// Make room for the tag so the data is encrypted in place.
+	plain.Grow(spec.TagSize / 8)
+	data := plain.Bytes()
+
+	cipherText, err := sealData(masterKey.withPassword(opts.Password), data[:0], data, authData, spec, c.logger)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encrypt data: %w", err)
+	}
+
+	head, err := json.Marshal(
+		EncryptedPaste{
+			V:     apiVersion,
+			AData: adata,
+			Meta:  EncryptedPasteMeta{Expire: expire},
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal paste request: %w", err)
+	}
+
+	// The cipher text is the last field of the envelope, it is base64
+	// encoded between the marshaled envelope and the closing quote.
+	head = bytes.TrimSuffix(head, []byte(`"}`))
+	tail := []byte(`"}`)
+
+	contentLength := int64(len(head)) +
+		int64(base64.StdEncoding.EncodedLen(len(cipherText))) +
+		int64(len(tail))
+
+	newBody := func() io.Reader {
+		pr, pw := io.Pipe()
+
+		go func() {
+			enc := base64.NewEncoder(base64.StdEncoding, pw)
+
+			_, err := pw.Write(head)
+			if err == nil {
+				_, err = enc.Write(cipherText)
+			}
+			if err == nil {
+				err = enc.Close()
+			}
+			if err == nil {
+				_, err = pw.Write(tail)
+			}
+
+			_ = pw.CloseWithError(err)
+		}()
+
+		return pr
+	}
+
+	return c.sendPaste(ctx, newBody, contentLength, masterKey, opts.BurnAfterReading)
+}
+
+// writePasteJSON writes the JSON encoding of the paste built from r and
+// opts, as Paste.MarshalJSON would, without loading r in memory.
+func writePasteJSON(w io.Writer, r io.Reader, opts CreatePasteOptions) error {
+	lr := &maxSizeReader{r: r, n: opts.MaxSize, maxSize: opts.MaxSize}
+
+	if opts.AttachmentName == "" {
+		// The attachment fields, if any, followed by the text.
+		fields, err := json.Marshal(Paste{Attachments: opts.Attachments})
+		if err != nil {
+			return fmt.Errorf("cannot json marshal paste content: %w", err)
+		}
+
+		fields = bytes.TrimSuffix(fields, []byte("}"))
+		if len(fields) > 1 {
+			fields = append(fields, ',')
+		}
+
+		if _, err := w.Write(append(fields, `"paste":"`...)); err != nil {
+			return fmt.Errorf("cannot write paste content: %w", err)
+		}
+
+		sw := &jsonStringWriter{w: w}
+		if _, err := io.Copy(sw, lr); err != nil {
+			return fmt.Errorf("cannot write paste content: %w", err)
+		}
+
+		if err := sw.Close(); err != nil {
+			return fmt.Errorf("cannot write paste content: %w", err)
+		}
+
+		if _, err := io.WriteString(w, `"}`); err != nil {
+			return fmt.Errorf("cannot write paste content: %w", err)
+		}
+
+		return nil
+	}
+
+	attachment := Attachment{Name: opts.AttachmentName}
+	names := []string{attachment.Name}
+	for _, a := range opts.Attachments {
+		names = append(names, a.Name)
+	}
+
+	// A single attachment uses the legacy string fields, several of them
+	// the array fields.
+	prefix, suffix := `"`, `"`
+	if len(opts.Attachments) > 0 {
+		prefix = `["`
+		for _, a := range opts.Attachments {
+			suffix += `,"` + a.dataURL() + `"`
+		}
+		suffix += `]`
+	}
+
+	if _, err := fmt.Fprintf(w, `{"attachment":%sdata:%s;base64,`, prefix, attachment.mimeType()); err != nil {
+		return fmt.Errorf("cannot write paste content: %w", err)
+	}
+
+	enc := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(enc, lr); err != nil {
+		return fmt.Errorf("cannot write paste content: %w", err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("cannot write paste content: %w", err)
+	}
+
+	var namesField any = names
+	if len(names) == 1 {
+		namesField = names[0]
+	}
+
+	rest := map[string]any{"attachment_name": namesField}
+	if len(opts.Message) > 0 {
+		rest["paste"] = string(opts.Message)
+	}
+
+	restJSON, err := json.Marshal(rest)
+	if err != nil {
+		return fmt.Errorf("cannot json marshal paste content: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "%s,%s", suffix, restJSON[1:]); err != nil {
+		return fmt.Errorf("cannot write paste content: %w", err)
+	}
+
+	return nil
+}
+
+func errPasteTooLarge(maxSize int64) error {
+	return fmt.Errorf("%w: more than %d bytes", ErrPasteTooLarge, maxSize)
+}
+
+// sizeHint returns the number of bytes left in r when it can be known
+// without reading it, -1 otherwise.
+func sizeHint(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return -1
+		}
+
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+
+		return fi.Size() - offset
+	}
+
+	return -1
+}
+
+// maxSizeReader reads from r and fails with ErrPasteTooLarge once
+// more than maxSize bytes have been read. A zero maxSize disables the
+// limit.
+type maxSizeReader struct {
+	r       io.Reader
+	n       int64
+	maxSize int64
+}
+
+func (l *maxSizeReader) Read(p []byte) (int, error) {
+	if l.maxSize <= 0 {
+		return l.r.Read(p)
+	}
+
+	if l.n < 0 {
+		return 0, errPasteTooLarge(l.maxSize)
+	}
+
+	// Read one byte past the limit to detect oversized input.
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, errPasteTooLarge(l.maxSize)
+	}
+
+	return n, err
+}
+
+// jsonStringWriter writes the JSON escaping of the bytes written to it,
+// without the surrounding quotes. A multi-byte character split between
+// two writes is kept until it is complete.
+type jsonStringWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (s *jsonStringWriter) Write(p []byte) (int, error) {
+	data := append(s.pending, p...)
+
+	end := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				end = i
+			}
+			break
+		}
+	}
+
+	if err := s.write(data[:end]); err != nil {
+		return 0, err
+	}
+
+	s.pending = append([]byte(nil), data[end:]...)
+	return len(p), nil
+}
+
+// Close writes the incomplete character left, if any.
+func (s *jsonStringWriter) Close() error {
+	err := s.write(s.pending)
+	s.pending = nil
+	return err
+}
+
+func (s *jsonStringWriter) write(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(string(data))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.w.Write(encoded[1 : len(encoded)-1])
+	return err
+}
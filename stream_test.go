@@ -0,0 +1,164 @@
+// Copyright (c) 2020-2026 Bryan Frimin <bryan@frimin.fr>.
+//
+// Permission to use, copy, modify, and/or distribute this software for any
+// purpose with or without fee is hereby granted, provided that the above
+// copyright notice and this permission notice appear in all copies.
+//
+// THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES WITH
+// REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF MERCHANTABILITY
+// AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR ANY SPECIAL, DIRECT,
+// INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES WHATSOEVER RESULTING FROM
+// LOSS OF USE, DATA OR PROFITS, WHETHER IN AN ACTION OF CONTRACT, NEGLIGENCE OR
+// OTHER TORTIOUS ACTION, ARISING OUT OF OR IN CONNECTION WITH THE USE OR
+// PERFORMANCE OF THIS SOFTWARE.
+
+package privatebin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CreatePasteFromReader(t *testing.T) {
+	text := strings.Repeat("héllo wörld 😀 <tag> \"quoted\"\n", 100)
+
+	tests := []struct {
+		name   string
+		reader func() io.Reader
+		opts   CreatePasteOptions
+		want   Paste
+	}{
+		{
+			name:   "Text read byte by byte",
+			reader: func() io.Reader { return iotest.OneByteReader(strings.NewReader(text)) },
+			want:   Paste{Data: []byte(text)},
+		},
+		{
+			name:   "Compressed text",
+			reader: func() io.Reader { return strings.NewReader(text) },
+			opts:   CreatePasteOptions{Compress: CompressionAlgorithmGZip},
+			want:   Paste{Data: []byte(text)},
+		},
+		{
+			name:   "Empty text",
+			reader: func() io.Reader { return strings.NewReader("") },
+			want:   Paste{Data: []byte{}},
+		},
+		{
+			name:   "Text with attachments",
+			reader: func() io.Reader { return strings.NewReader("hello") },
+			opts: CreatePasteOptions{
+				Attachments: []Attachment{{Name: "a.txt", Data: []byte("a")}},
+			},
+			want: Paste{
+				Data:        []byte("hello"),
+				Attachments: []Attachment{{Name: "a.txt", MimeType: "text/plain; charset=utf-8", Data: []byte("a")}},
+			},
+		},
+		{
+			name:   "Single attachment with message",
+			reader: func() io.Reader { return iotest.HalfReader(strings.NewReader(text)) },
+			opts: CreatePasteOptions{
+				AttachmentName: "log.txt",
+				Message:        []byte("see attached"),
+				Compress:       CompressionAlgorithmGZip,
+			},
+			want: Paste{
+				Data:        []byte("see attached"),
+				Attachments: []Attachment{{Name: "log.txt", MimeType: "text/plain; charset=utf-8", Data: []byte(text)}},
+			},
+		},
+		{
+			name:   "Several attachments",
+			reader: func() io.Reader { return bytes.NewReader([]byte{0, 1, 2}) },
+			opts: CreatePasteOptions{
+				AttachmentName: "first.bin",
+				Attachments:    []Attachment{{Name: "second.bin", MimeType: "application/x-test", Data: []byte{3}}},
+			},
+			want: Paste{
+				Data: []byte{},
+				Attachments: []Attachment{
+					{Name: "first.bin", MimeType: "application/octet-stream", Data: []byte{0, 1, 2}},
+					{Name: "second.bin", MimeType: "application/x-test", Data: []byte{3}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received EncryptedPaste
+
+			server := httptest.NewServer(
+				http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						body, err := io.ReadAll(r.Body)
+						require.NoError(t, err)
+						assert.Equal(t, r.ContentLength, int64(len(body)))
+						require.NoError(t, json.Unmarshal(body, &received))
+
+						_, _ = io.WriteString(w, `{"status":0,"id":"f468483c313401e8","url":"/?f468483c313401e8","deletetoken":"token"}`)
+					},
+				),
+			)
+			defer server.Close()
+
+			endpoint, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			tt.opts.Expire = Expire1Day
+
+			client := NewClient(*endpoint)
+			result, err := client.CreatePasteFromReader(context.Background(), tt.reader(), tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, "f468483c313401e8", result.PasteID)
+			assert.Equal(t, "1day", received.Meta.Expire)
+
+			masterKey, err := ParseMasterKey(result.PasteURL.Fragment)
+			require.NoError(t, err)
+
+			paste, err := Open(received, masterKey, nil)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.want.Data), string(paste.Data))
+			assert.Equal(t, tt.want.Attachments, paste.Attachments)
+		})
+	}
+}
+
+func TestClient_CreatePasteFromReader_MaxSize(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				t.Error("unexpected request")
+			},
+		),
+	)
+	defer server.Close()
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewClient(*endpoint)
+	opts := CreatePasteOptions{AttachmentName: "big", MaxSize: 10}
+
+	_, err = client.CreatePasteFromReader(context.Background(), strings.NewReader(strings.Repeat("x", 11)), opts)
+	require.ErrorIs(t, err, ErrPasteTooLarge)
+
+	_, err = client.CreatePasteFromReader(
+		context.Background(),
+		iotest.OneByteReader(strings.NewReader(strings.Repeat("x", 11))),
+		opts,
+	)
+	require.ErrorIs(t, err, ErrPasteTooLarge)
+}
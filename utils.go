@@ -53,7 +53,7 @@ func decode64(s string) ([]byte, error) {
 	return base64.RawStdEncoding.DecodeString(s)
 }
 
-func defaultPooledClient(tlsConfig *tls.Config, proxyURL *url.URL) *http.Client {
+func defaultPooledTransport(tlsConfig *tls.Config, proxyURL *url.URL) *http.Transport {
 	dial := &net.Dialer{
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
@@ -65,7 +65,7 @@ func defaultPooledClient(tlsConfig *tls.Config, proxyURL *url.URL) *http.Client
 		proxyFunc = http.ProxyURL(proxyURL)
 	}
 
-	transport := &http.Transport{
+	return &http.Transport{
 		Proxy:                 proxyFunc,
 		DialContext:           dial.DialContext,
 		MaxIdleConns:          100,
@@ -76,7 +76,4 @@ func defaultPooledClient(tlsConfig *tls.Config, proxyURL *url.URL) *http.Client
 		MaxIdleConnsPerHost:   runtime.GOMAXPROCS(0) + 1,
 		TLSClientConfig:       tlsConfig,
 	}
-
-	return &http.Client{Transport: transport}
 }
-